@@ -0,0 +1,95 @@
+package bluez
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// GetAdapterProperties gathers all the properties for an adapter.
+func (b *Bluez) GetAdapterProperties(adapterName string) (map[string]dbus.Variant, error) {
+	result := make(map[string]dbus.Variant)
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	if err := b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.bluez.Adapter1").Store(&result); err != nil {
+		return result, ConvertError(err)
+	}
+	return result, nil
+}
+
+// SetAdapterPropertyAndWait sets an adapter property and waits for bluez to
+// report the new value, as some changes like powering on only happen after
+// the call returns.
+func (b *Bluez) SetAdapterPropertyAndWait(adapterName, key string, value interface{}, timeout time.Duration) error {
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+
+	// Watch before setting so the change isn't missed, and check the
+	// current value as no signal is sent when nothing changes.
+	signalChan, stop := b.WatchPropertiesChanged()
+	defer stop()
+	props, err := b.GetAdapterProperties(adapterName)
+	if err != nil {
+		return err
+	}
+	if props[key].Value() == value {
+		return nil
+	}
+	if err := b.SetAdapterProperty(adapterName, key, value); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case s := <-signalChan:
+			iface, changed, ok := PropertiesChanged(s)
+			if !ok || s.Path != path || iface != "org.bluez.Adapter1" {
+				continue
+			}
+			if v, ok := changed[key]; ok && v.Value() == value {
+				return nil
+			}
+		case <-timer.C:
+			return &TimeoutError{Waiting: fmt.Sprintf("%s to change to %v", key, value)}
+		}
+	}
+}
+
+// TimeoutError is returned when bluez doesn't report an expected change
+// in time.
+type TimeoutError struct {
+	Waiting string
+}
+
+func (e *TimeoutError) Error() string {
+	return "timed out waiting for " + e.Waiting
+}
+
+// Timeout reports that the error is a timeout.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// StopWatching stops signals being sent to a channel returned by
+// WatchSignal.
+func (b *Bluez) StopWatching(ch chan *dbus.Signal) {
+	RemoveSignal(b.conn, ch)
+}
+
+// RemoveSignal unregisters a signal channel from conn. The channel is
+// drained while removing it, as signals are delivered with a blocking send.
+func RemoveSignal(conn *dbus.Conn, ch chan *dbus.Signal) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+			case <-done:
+				return
+			}
+		}
+	}()
+	conn.RemoveSignal(ch)
+	close(done)
+}
@@ -0,0 +1,203 @@
+package bluez
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A2DP codec identifiers as used by MediaTransport1.Codec.
+// https://www.bluetooth.com/specifications/assigned-numbers/
+const (
+	CodecSBC    byte = 0x00
+	CodecMPEG12 byte = 0x01
+	CodecAAC    byte = 0x02
+	CodecVendor byte = 0xff
+)
+
+// Vendor specific codecs are identified by a vendor id and a codec id
+// at the start of the configuration.
+const (
+	vendorAPTX   uint32 = 0x0000004f
+	vendorAPTXHD uint32 = 0x000000d7
+	vendorLDAC   uint32 = 0x0000012d
+
+	codecAPTX   uint16 = 0x0001
+	codecAPTXHD uint16 = 0x0024
+	codecLDAC   uint16 = 0x00aa
+)
+
+// CodecParameter is a single decoded value of a codec configuration.
+type CodecParameter struct {
+	Name  string
+	Value string
+}
+
+// CodecConfiguration is the human readable form of the codec and its
+// configuration negotiated for a media transport.
+type CodecConfiguration struct {
+	Name       string
+	Parameters []CodecParameter
+}
+
+func (c CodecConfiguration) String() string {
+	params := make([]string, 0, len(c.Parameters))
+	for _, p := range c.Parameters {
+		params = append(params, fmt.Sprintf("%s=%s", p.Name, p.Value))
+	}
+	if len(params) == 0 {
+		return c.Name
+	}
+	return fmt.Sprintf("%s (%s)", c.Name, strings.Join(params, " "))
+}
+
+// bitValue maps a bit in a configuration byte to the value it represents.
+type bitValue struct {
+	bit   byte
+	value string
+}
+
+// bitValues returns the values for every bit set in b, a configuration
+// should only ever have a single bit set but capabilities can have many.
+func bitValues(b byte, values []bitValue) string {
+	found := []string{}
+	for _, v := range values {
+		if b&v.bit != 0 {
+			found = append(found, v.value)
+		}
+	}
+	if len(found) == 0 {
+		return "unknown"
+	}
+	return strings.Join(found, "|")
+}
+
+var (
+	sbcFrequencies = []bitValue{{0x80, "16000"}, {0x40, "32000"}, {0x20, "44100"}, {0x10, "48000"}}
+	sbcChannelMode = []bitValue{{0x08, "mono"}, {0x04, "dual-channel"}, {0x02, "stereo"}, {0x01, "joint-stereo"}}
+	sbcBlockLength = []bitValue{{0x80, "4"}, {0x40, "8"}, {0x20, "12"}, {0x10, "16"}}
+	sbcSubbands    = []bitValue{{0x08, "4"}, {0x04, "8"}}
+	sbcAllocation  = []bitValue{{0x02, "snr"}, {0x01, "loudness"}}
+
+	mpegLayers      = []bitValue{{0x80, "mp1"}, {0x40, "mp2"}, {0x20, "mp3"}}
+	mpegFrequencies = []bitValue{{0x20, "16000"}, {0x10, "22050"}, {0x08, "24000"}, {0x04, "32000"}, {0x02, "44100"}, {0x01, "48000"}}
+
+	aacObjectTypes  = []bitValue{{0x80, "mpeg2-aac-lc"}, {0x40, "mpeg4-aac-lc"}, {0x20, "mpeg4-aac-ltp"}, {0x10, "mpeg4-aac-scalable"}}
+	aacFrequencies1 = []bitValue{{0x80, "8000"}, {0x40, "11025"}, {0x20, "12000"}, {0x10, "16000"}, {0x08, "22050"}, {0x04, "24000"}, {0x02, "32000"}, {0x01, "44100"}}
+	aacFrequencies2 = []bitValue{{0x80, "48000"}, {0x40, "64000"}, {0x20, "88200"}, {0x10, "96000"}}
+	aacChannels     = []bitValue{{0x08, "1"}, {0x04, "2"}}
+
+	aptxFrequencies = []bitValue{{0x80, "16000"}, {0x40, "32000"}, {0x20, "44100"}, {0x10, "48000"}}
+	aptxChannelMode = []bitValue{{0x01, "mono"}, {0x02, "stereo"}}
+
+	ldacFrequencies = []bitValue{{0x20, "44100"}, {0x10, "48000"}, {0x08, "88200"}, {0x04, "96000"}, {0x02, "176400"}, {0x01, "192000"}}
+	ldacChannelMode = []bitValue{{0x04, "mono"}, {0x02, "dual-channel"}, {0x01, "stereo"}}
+)
+
+// DecodeCodecConfiguration decodes the raw A2DP codec configuration for a
+// media transport. Codecs that are not known are returned with only their
+// identifiers.
+func DecodeCodecConfiguration(codec byte, config []byte) (CodecConfiguration, error) {
+	switch codec {
+	case CodecSBC:
+		if len(config) < 4 {
+			return CodecConfiguration{}, fmt.Errorf("sbc configuration is %d bytes, expected 4", len(config))
+		}
+		return CodecConfiguration{
+			Name: "SBC",
+			Parameters: []CodecParameter{
+				{"frequency", bitValues(config[0]&0xf0, sbcFrequencies)},
+				{"channel-mode", bitValues(config[0]&0x0f, sbcChannelMode)},
+				{"block-length", bitValues(config[1]&0xf0, sbcBlockLength)},
+				{"subbands", bitValues(config[1]&0x0c, sbcSubbands)},
+				{"allocation", bitValues(config[1]&0x03, sbcAllocation)},
+				{"min-bitpool", fmt.Sprintf("%d", config[2])},
+				{"max-bitpool", fmt.Sprintf("%d", config[3])},
+			},
+		}, nil
+	case CodecMPEG12:
+		if len(config) < 4 {
+			return CodecConfiguration{}, fmt.Errorf("mpeg configuration is %d bytes, expected 4", len(config))
+		}
+		return CodecConfiguration{
+			Name: "MPEG",
+			Parameters: []CodecParameter{
+				{"layer", bitValues(config[0]&0xe0, mpegLayers)},
+				{"crc", fmt.Sprintf("%t", config[0]&0x10 != 0)},
+				{"channel-mode", bitValues(config[0]&0x0f, sbcChannelMode)},
+				{"frequency", bitValues(config[1]&0x3f, mpegFrequencies)},
+				{"vbr", fmt.Sprintf("%t", config[2]&0x80 != 0)},
+			},
+		}, nil
+	case CodecAAC:
+		if len(config) < 6 {
+			return CodecConfiguration{}, fmt.Errorf("aac configuration is %d bytes, expected 6", len(config))
+		}
+		frequency := bitValues(config[1], aacFrequencies1)
+		if frequency == "unknown" {
+			frequency = bitValues(config[2]&0xf0, aacFrequencies2)
+		}
+		bitrate := uint32(config[3]&0x7f)<<16 | uint32(config[4])<<8 | uint32(config[5])
+		return CodecConfiguration{
+			Name: "AAC",
+			Parameters: []CodecParameter{
+				{"object-type", bitValues(config[0], aacObjectTypes)},
+				{"frequency", frequency},
+				{"channels", bitValues(config[2]&0x0c, aacChannels)},
+				{"vbr", fmt.Sprintf("%t", config[3]&0x80 != 0)},
+				{"bitrate", fmt.Sprintf("%d", bitrate)},
+			},
+		}, nil
+	case CodecVendor:
+		return decodeVendorCodec(config)
+	}
+	return CodecConfiguration{
+		Name:       fmt.Sprintf("unknown codec 0x%02x", codec),
+		Parameters: []CodecParameter{{"configuration", fmt.Sprintf("%x", config)}},
+	}, nil
+}
+
+func decodeVendorCodec(config []byte) (CodecConfiguration, error) {
+	if len(config) < 6 {
+		return CodecConfiguration{}, fmt.Errorf("vendor configuration is %d bytes, expected at least 6", len(config))
+	}
+	vendorID := uint32(config[0]) | uint32(config[1])<<8 | uint32(config[2])<<16 | uint32(config[3])<<24
+	codecID := uint16(config[4]) | uint16(config[5])<<8
+	rest := config[6:]
+
+	switch {
+	case vendorID == vendorAPTX && codecID == codecAPTX, vendorID == vendorAPTXHD && codecID == codecAPTXHD:
+		name := "aptX"
+		if vendorID == vendorAPTXHD {
+			name = "aptX HD"
+		}
+		if len(rest) < 1 {
+			return CodecConfiguration{}, fmt.Errorf("%s configuration is missing frequency and channel mode", name)
+		}
+		return CodecConfiguration{
+			Name: name,
+			Parameters: []CodecParameter{
+				{"frequency", bitValues(rest[0]&0xf0, aptxFrequencies)},
+				{"channel-mode", bitValues(rest[0]&0x0f, aptxChannelMode)},
+			},
+		}, nil
+	case vendorID == vendorLDAC && codecID == codecLDAC:
+		if len(rest) < 2 {
+			return CodecConfiguration{}, fmt.Errorf("ldac configuration is %d bytes, expected 8", len(config))
+		}
+		return CodecConfiguration{
+			Name: "LDAC",
+			Parameters: []CodecParameter{
+				{"frequency", bitValues(rest[0]&0x3f, ldacFrequencies)},
+				{"channel-mode", bitValues(rest[1]&0x07, ldacChannelMode)},
+			},
+		}, nil
+	}
+	return CodecConfiguration{
+		Name: "vendor",
+		Parameters: []CodecParameter{
+			{"vendor-id", fmt.Sprintf("0x%08x", vendorID)},
+			{"codec-id", fmt.Sprintf("0x%04x", codecID)},
+			{"configuration", fmt.Sprintf("%x", rest)},
+		},
+	}, nil
+}
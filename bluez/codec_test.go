@@ -0,0 +1,111 @@
+package bluez
+
+import "testing"
+
+func TestDecodeCodecConfiguration(t *testing.T) {
+	tests := []struct {
+		name   string
+		codec  byte
+		config []byte
+		want   string
+	}{
+		{
+			name:   "sbc",
+			codec:  CodecSBC,
+			config: []byte{0x21, 0x15, 2, 53},
+			want:   "SBC (frequency=44100 channel-mode=joint-stereo block-length=16 subbands=8 allocation=loudness min-bitpool=2 max-bitpool=53)",
+		},
+		{
+			name:   "sbc without allocation",
+			codec:  CodecSBC,
+			config: []byte{0x18, 0x88, 2, 32},
+			want:   "SBC (frequency=48000 channel-mode=mono block-length=4 subbands=4 allocation=unknown min-bitpool=2 max-bitpool=32)",
+		},
+		{
+			name:   "sbc capabilities",
+			codec:  CodecSBC,
+			config: []byte{0xff, 0xff, 2, 250},
+			want:   "SBC (frequency=16000|32000|44100|48000 channel-mode=mono|dual-channel|stereo|joint-stereo block-length=4|8|12|16 subbands=4|8 allocation=snr|loudness min-bitpool=2 max-bitpool=250)",
+		},
+		{
+			name:   "mpeg",
+			codec:  CodecMPEG12,
+			config: []byte{0x32, 0x02, 0x80, 0x00},
+			want:   "MPEG (layer=mp3 crc=true channel-mode=stereo frequency=44100 vbr=true)",
+		},
+		{
+			name:   "aac 44100",
+			codec:  CodecAAC,
+			config: []byte{0x80, 0x01, 0x04, 0x83, 0xe8, 0x00},
+			want:   "AAC (object-type=mpeg2-aac-lc frequency=44100 channels=2 vbr=true bitrate=256000)",
+		},
+		{
+			name:   "aac 48000",
+			codec:  CodecAAC,
+			config: []byte{0x40, 0x00, 0x88, 0x01, 0xf4, 0x00},
+			want:   "AAC (object-type=mpeg4-aac-lc frequency=48000 channels=1 vbr=false bitrate=128000)",
+		},
+		{
+			name:   "aptx",
+			codec:  CodecVendor,
+			config: []byte{0x4f, 0, 0, 0, 0x01, 0, 0x22},
+			want:   "aptX (frequency=44100 channel-mode=stereo)",
+		},
+		{
+			name:   "aptx hd",
+			codec:  CodecVendor,
+			config: []byte{0xd7, 0, 0, 0, 0x24, 0, 0x12},
+			want:   "aptX HD (frequency=48000 channel-mode=stereo)",
+		},
+		{
+			name:   "ldac",
+			codec:  CodecVendor,
+			config: []byte{0x2d, 0x01, 0, 0, 0xaa, 0, 0x08, 0x01},
+			want:   "LDAC (frequency=88200 channel-mode=stereo)",
+		},
+		{
+			name:   "unknown vendor codec",
+			codec:  CodecVendor,
+			config: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0xaa, 0xbb},
+			want:   "vendor (vendor-id=0x04030201 codec-id=0x0605 configuration=aabb)",
+		},
+		{
+			name:   "unknown codec",
+			codec:  0x03,
+			config: []byte{0x01, 0x02},
+			want:   "unknown codec 0x03 (configuration=0102)",
+		},
+	}
+	for _, tt := range tests {
+		got, err := DecodeCodecConfiguration(tt.codec, tt.config)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCodecConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		codec  byte
+		config []byte
+		want   string
+	}{
+		{"short sbc", CodecSBC, []byte{0x21, 0x15, 2}, "sbc configuration is 3 bytes, expected 4"},
+		{"short mpeg", CodecMPEG12, []byte{0x32, 0x02}, "mpeg configuration is 2 bytes, expected 4"},
+		{"short aac", CodecAAC, []byte{0x80, 0x01, 0x04, 0x83, 0xe8}, "aac configuration is 5 bytes, expected 6"},
+		{"short vendor", CodecVendor, []byte{0x4f, 0, 0, 0, 0x01}, "vendor configuration is 5 bytes, expected at least 6"},
+		{"short aptx", CodecVendor, []byte{0x4f, 0, 0, 0, 0x01, 0}, "aptX configuration is missing frequency and channel mode"},
+		{"short ldac", CodecVendor, []byte{0x2d, 0x01, 0, 0, 0xaa, 0, 0x08}, "ldac configuration is 7 bytes, expected 8"},
+	}
+	for _, tt := range tests {
+		_, err := DecodeCodecConfiguration(tt.codec, tt.config)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package bluez
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/godbus/dbus"
 )
@@ -18,27 +19,53 @@ const (
 // Adapter holds the bluetooth device adapter installed for a system.
 // This can be retrieved by `hciconfig -a`.
 type Adapter struct {
-	Path         string
-	Name         string
-	Alias        string
-	Address      string
-	Discoverable bool
-	Pairable     bool
-	Powered      bool
-	Discovering  bool
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	Alias        string `json:"alias"`
+	Address      string `json:"address"`
+	Discoverable bool   `json:"discoverable"`
+	Pairable     bool   `json:"pairable"`
+	Powered      bool   `json:"powered"`
+	Discovering  bool   `json:"discovering"`
 }
 
 // Device hold bluetooth device information.
 type Device struct {
-	Path      string
-	Name      string
-	Alias     string
-	Address   string
-	Adapter   string
-	Paired    bool
-	Connected bool
-	Trusted   bool
-	Blocked   bool
+	Path      string   `json:"path"`
+	Name      string   `json:"name"`
+	Alias     string   `json:"alias"`
+	Address   string   `json:"address"`
+	Adapter   string   `json:"adapter"`
+	Paired    bool     `json:"paired"`
+	Connected bool     `json:"connected"`
+	Trusted   bool     `json:"trusted"`
+	Blocked   bool     `json:"blocked"`
+	UUIDs     []string `json:"uuids"`
+}
+
+// DisplayName returns the alias of the device, falling back to its name
+// and then its address.
+func (d Device) DisplayName() string {
+	switch {
+	case d.Alias != "":
+		return d.Alias
+	case d.Name != "":
+		return d.Name
+	}
+	return d.Address
+}
+
+// AudioSinkUUID is the service UUID of devices that can play A2DP audio.
+const AudioSinkUUID = "0000110b-0000-1000-8000-00805f9b34fb"
+
+// HasUUID reports whether the device advertises the service uuid.
+func (d Device) HasUUID(uuid string) bool {
+	for _, u := range d.UUIDs {
+		if strings.EqualFold(u, uuid) {
+			return true
+		}
+	}
+	return false
 }
 
 // Bluez represents an overview of the bluetooth adapters and
@@ -48,8 +75,9 @@ type Device struct {
 type Bluez struct {
 	conn *dbus.Conn
 
-	Adapters []Adapter
-	Devices  []Device
+	Adapters   []Adapter
+	Devices    []Device
+	Transports []MediaTransport
 }
 
 // NewBluez returns a new Bluez
@@ -83,9 +111,12 @@ func (b *Bluez) ConvertToDevices(path string, values map[string]map[string]dbus.
 		switch k {
 		case "org.bluez.Device1":
 			adapter, _ := v["Adapter"].Value().(dbus.ObjectPath)
+			uuids, _ := v["UUIDs"].Value().([]string)
+			// LE devices seen through advertisements don't always have a name.
+			name, _ := v["Name"].Value().(string)
 			devices = append(devices, Device{
 				Path:      path,
-				Name:      v["Name"].Value().(string),
+				Name:      name,
 				Alias:     v["Alias"].Value().(string),
 				Address:   v["Address"].Value().(string),
 				Adapter:   string(adapter),
@@ -93,6 +124,7 @@ func (b *Bluez) ConvertToDevices(path string, values map[string]map[string]dbus.
 				Connected: v["Connected"].Value().(bool),
 				Trusted:   v["Trusted"].Value().(bool),
 				Blocked:   v["Blocked"].Value().(bool),
+				UUIDs:     uuids,
 			})
 		}
 	}
@@ -110,8 +142,10 @@ func (b *Bluez) PopulateCache() error {
 	}
 	devices := []Device{}
 	adapters := []Adapter{}
+	transports := []MediaTransport{}
 	for k, v := range results {
 		devices = append(devices, b.ConvertToDevices(string(k), v)...)
+		transports = append(transports, b.ConvertToMediaTransports(string(k), v)...)
 		for k1, v1 := range v {
 			switch k1 {
 			case "org.bluez.Adapter1":
@@ -149,16 +183,39 @@ func (b *Bluez) PopulateCache() error {
 
 	b.Adapters = adapters
 	b.Devices = devices
+	b.Transports = transports
 
 	return nil
 }
 
+// FindDevice returns the cached device with the mac address, false is
+// returned if bluez doesn't know about the device.
+func (b *Bluez) FindDevice(deviceMac string) (Device, bool) {
+	for _, d := range b.Devices {
+		if strings.EqualFold(d.Address, deviceMac) {
+			return d, true
+		}
+	}
+	return Device{}, false
+}
+
+// FindAdapter returns the cached adapter with the name, ie: "hci0", false
+// is returned if bluez doesn't know about the adapter.
+func (b *Bluez) FindAdapter(adapterName string) (Adapter, bool) {
+	for _, a := range b.Adapters {
+		if a.Path == "/org/bluez/"+adapterName {
+			return a, true
+		}
+	}
+	return Adapter{}, false
+}
+
 // ManagedObjects gets all bluetooth devices and adpaters that are currently
 // managed by bluez.
 func (b *Bluez) ManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, error) {
 	result := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant)
 	if err := b.conn.Object(dbusBluetoothPath, "/").Call(dbusObjectManagerPath, 0).Store(&result); err != nil {
-		return result, err
+		return result, ConvertError(err)
 	}
 	return result, nil
 }
@@ -175,7 +232,16 @@ func (b *Bluez) CallAdapter(adapter, method string, flags dbus.Flags, args ...in
 // that are in pairing mode.
 func (b *Bluez) StartDiscovery(adapter string) error {
 	if err := b.CallAdapter(adapter, "StartDiscovery", 0).Store(); err != nil {
-		return err
+		return ConvertError(err)
+	}
+	return nil
+}
+
+// StopDiscovery stops the discovery started by StartDiscovery, bluez keeps
+// discovering while other clients want it to.
+func (b *Bluez) StopDiscovery(adapter string) error {
+	if err := b.CallAdapter(adapter, "StopDiscovery", 0).Store(); err != nil {
+		return ConvertError(err)
 	}
 	return nil
 }
@@ -186,7 +252,7 @@ func (b *Bluez) StartDiscovery(adapter string) error {
 func (b *Bluez) RemoveDevice(adapterName, deviceMac string) error {
 	devicePath := b.devicePath(adapterName, deviceMac)
 	if err := b.CallAdapter(adapterName, "RemoveDevice", 0, devicePath).Store(); err != nil {
-		return err
+		return ConvertError(err)
 	}
 	return nil
 }
@@ -202,6 +268,57 @@ func (b *Bluez) WatchSignal() chan *dbus.Signal {
 	return ch
 }
 
+// WatchPropertiesChanged will register to receive property changes for all
+// bluez objects, ie: a device connecting. Any events received are passed
+// along to the returned channel for the caller to use, stop must be called
+// once done to remove the match rule and the channel.
+func (b *Bluez) WatchPropertiesChanged() (ch chan *dbus.Signal, stop func()) {
+	signalMatch := "type='signal',sender='org.bluez',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'"
+	b.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, signalMatch)
+	// Every property change of every object arrives here, buffer them so
+	// a slow reader doesn't hold up the connection.
+	ch = make(chan *dbus.Signal, 16)
+	b.conn.Signal(ch)
+	return ch, func() {
+		b.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, signalMatch)
+		b.StopWatching(ch)
+	}
+}
+
+// PropertiesChanged returns the interface and changed properties of a
+// PropertiesChanged signal, false is returned for any other signal.
+func PropertiesChanged(signal *dbus.Signal) (string, map[string]dbus.Variant, bool) {
+	if signal.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(signal.Body) < 2 {
+		return "", nil, false
+	}
+	iface, ok := signal.Body[0].(string)
+	if !ok {
+		return "", nil, false
+	}
+	changed, ok := signal.Body[1].(map[string]dbus.Variant)
+	if !ok {
+		return "", nil, false
+	}
+	return iface, changed, true
+}
+
+// Reasons given by a device Disconnected signal.
+const (
+	DisconnectLocal  = "org.bluez.Reason.Local"
+	DisconnectRemote = "org.bluez.Reason.Remote"
+)
+
+// Disconnected returns the reason of a device Disconnected signal, ie:
+// DisconnectLocal when this host disconnected the device. False is returned
+// for any other signal, bluez only sends these since 5.72.
+func Disconnected(signal *dbus.Signal) (string, bool) {
+	if signal.Name != "org.bluez.Device1.Disconnected" || len(signal.Body) < 1 {
+		return "", false
+	}
+	reason, ok := signal.Body[0].(string)
+	return reason, ok
+}
+
 // devicePath will normalise the device path
 func (b *Bluez) devicePath(adapterName, deviceMac string) dbus.ObjectPath {
 	path := fmt.Sprintf(
@@ -212,6 +329,11 @@ func (b *Bluez) devicePath(adapterName, deviceMac string) dbus.ObjectPath {
 	return dbus.ObjectPath(path)
 }
 
+// DevicePath returns the bluez object path of a device.
+func (b *Bluez) DevicePath(adapterName, deviceMac string) dbus.ObjectPath {
+	return b.devicePath(adapterName, deviceMac)
+}
+
 // CallDevice is used to interact with the bluez Device dbus interface.
 // https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/device-api.txt
 func (b *Bluez) CallDevice(adapterName, deviceMac, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
@@ -221,18 +343,32 @@ func (b *Bluez) CallDevice(adapterName, deviceMac, method string, flags dbus.Fla
 
 // Pair will attempt to pair a bluetooth device that is in pairing mode.
 func (b *Bluez) Pair(adapterName, deviceMac string) error {
-	return b.CallDevice(adapterName, deviceMac, "Pair", 0).Store()
+	return ConvertError(b.CallDevice(adapterName, deviceMac, "Pair", 0).Store())
+}
+
+// PairAndWait pairs a device like Pair, a device that is already paired
+// isn't an error. When pairing is already in progress, ie: started by an
+// earlier run that was interrupted, it is waited for for up to timeout.
+func (b *Bluez) PairAndWait(adapterName, deviceMac string, timeout time.Duration) error {
+	err := b.Pair(adapterName, deviceMac)
+	switch {
+	case Is(err, ErrAlreadyExists):
+		return nil
+	case Is(err, ErrInProgress):
+		return b.waitInProgress(adapterName, deviceMac, "Paired", "pairing", timeout)
+	}
+	return err
 }
 
 // Connect will attempt to connect an already paired bluetooth device
 // to an adapter.
 func (b *Bluez) Connect(adapterName, deviceMac string) error {
-	return b.CallDevice(adapterName, deviceMac, "Connect", 0).Store()
+	return ConvertError(b.CallDevice(adapterName, deviceMac, "Connect", 0).Store())
 }
 
 // Disconnect will remove the bluetooth device from the adapter.
 func (b *Bluez) Disconnect(adapterName, deviceMac string) error {
-	return b.CallDevice(adapterName, deviceMac, "Disconnect", 0).Store()
+	return ConvertError(b.CallDevice(adapterName, deviceMac, "Disconnect", 0).Store())
 }
 
 // GetDeviceProperties gathers all the properties for a bluetooth device.
@@ -241,7 +377,7 @@ func (b *Bluez) GetDeviceProperties(adapterName, deviceMac string) (map[string]d
 	path := b.devicePath(adapterName, deviceMac)
 	// TODO(vishen): factor this with the CallDevice functionality
 	if err := b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.bluez.Device1").Store(&result); err != nil {
-		return result, err
+		return result, ConvertError(err)
 	}
 	return result, nil
 }
@@ -250,11 +386,23 @@ func (b *Bluez) GetDeviceProperties(adapterName, deviceMac string) (map[string]d
 func (b *Bluez) SetDeviceProperty(adapterName, deviceMac string, key string, value interface{}) error {
 	path := b.devicePath(adapterName, deviceMac)
 	// TODO(vishen): factor this with the CallDevice functionality
-	return b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.Set", 0, "org.bluez.Device1", key, dbus.MakeVariant(value)).Store()
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.Set", 0, "org.bluez.Device1", key, dbus.MakeVariant(value)).Store())
 }
 
 // SetAdapterProperty can be used to set certain properties for a bluetooth device.
 func (b *Bluez) SetAdapterProperty(adapterName, key string, value interface{}) error {
 	path := dbus.ObjectPath("/org/bluez/" + adapterName)
-	return b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.Set", 0, "org.bluez.Adapter1", key, dbus.MakeVariant(value)).Store()
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.Set", 0, "org.bluez.Adapter1", key, dbus.MakeVariant(value)).Store())
+}
+
+// SetDeviceAlias sets the alias of a device, an empty alias resets it to
+// the name of the device.
+func (b *Bluez) SetDeviceAlias(adapterName, deviceMac, alias string) error {
+	return b.SetDeviceProperty(adapterName, deviceMac, "Alias", alias)
+}
+
+// SetAdapterAlias sets the alias of an adapter, an empty alias resets it
+// to the system name of the adapter.
+func (b *Bluez) SetAdapterAlias(adapterName, alias string) error {
+	return b.SetAdapterProperty(adapterName, "Alias", alias)
 }
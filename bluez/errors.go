@@ -0,0 +1,196 @@
+package bluez
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+// Errors returned for bluez error replies, check for them with errors.Is.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/device-api.txt
+var (
+	ErrInProgress              = errors.New("operation already in progress")
+	ErrAlreadyConnected        = errors.New("already connected")
+	ErrAlreadyExists           = errors.New("already exists")
+	ErrNotConnected            = errors.New("not connected")
+	ErrAuthenticationFailed    = errors.New("authentication failed")
+	ErrAuthenticationCanceled  = errors.New("authentication canceled")
+	ErrAuthenticationRejected  = errors.New("authentication rejected")
+	ErrAuthenticationTimeout   = errors.New("authentication timed out")
+	ErrConnectionAttemptFailed = errors.New("connection attempt failed")
+	ErrPageTimeout             = errors.New("device did not respond")
+	ErrProfileUnavailable      = errors.New("no profile available")
+	ErrNotReady                = errors.New("adapter not ready")
+	ErrNotAvailable            = errors.New("not available")
+	ErrDoesNotExist            = errors.New("does not exist")
+	ErrNotSupported            = errors.New("not supported")
+	ErrInvalidArguments        = errors.New("invalid arguments")
+	ErrAccessDenied            = errors.New("access denied")
+	ErrNotRunning              = errors.New("bluetoothd is not running")
+	ErrFailed                  = errors.New("operation failed")
+)
+
+// errorNames maps dbus error names to the errors above.
+var errorNames = map[string]error{
+	"org.bluez.Error.InProgress":                  ErrInProgress,
+	"org.bluez.Error.AlreadyConnected":            ErrAlreadyConnected,
+	"org.bluez.Error.AlreadyExists":               ErrAlreadyExists,
+	"org.bluez.Error.NotConnected":                ErrNotConnected,
+	"org.bluez.Error.AuthenticationFailed":        ErrAuthenticationFailed,
+	"org.bluez.Error.AuthenticationCanceled":      ErrAuthenticationCanceled,
+	"org.bluez.Error.AuthenticationRejected":      ErrAuthenticationRejected,
+	"org.bluez.Error.AuthenticationTimeout":       ErrAuthenticationTimeout,
+	"org.bluez.Error.ConnectionAttemptFailed":     ErrConnectionAttemptFailed,
+	"org.bluez.Error.NotReady":                    ErrNotReady,
+	"org.bluez.Error.NotAvailable":                ErrNotAvailable,
+	"org.bluez.Error.DoesNotExist":                ErrDoesNotExist,
+	"org.bluez.Error.NotSupported":                ErrNotSupported,
+	"org.bluez.Error.InvalidArguments":            ErrInvalidArguments,
+	"org.bluez.Error.NotAuthorized":               ErrAccessDenied,
+	"org.bluez.Error.NotPermitted":                ErrAccessDenied,
+	"org.bluez.Error.Failed":                      ErrFailed,
+	"org.freedesktop.DBus.Error.AccessDenied":     ErrAccessDenied,
+	"org.freedesktop.DBus.Error.ServiceUnknown":   ErrNotRunning,
+	"org.freedesktop.DBus.Error.NameHasNoOwner":   ErrNotRunning,
+	"org.freedesktop.DBus.Error.UnknownObject":    ErrDoesNotExist,
+	"org.freedesktop.DBus.Error.UnknownMethod":    ErrNotSupported,
+	"org.freedesktop.DBus.Error.InvalidArgs":      ErrInvalidArguments,
+	"org.freedesktop.DBus.Error.NotSupported":     ErrNotSupported,
+	"org.freedesktop.DBus.Error.PropertyReadOnly": ErrNotSupported,
+}
+
+// failedMessages refines org.bluez.Error.Failed, and the other errors bluez
+// reuses, by their message. Newer versions of bluez send the connection
+// error codes, ie: "br-connection-page-timeout", older versions send the
+// strerror of the kernel error.
+var failedMessages = []struct {
+	contains string
+	err      error
+}{
+	{"page-timeout", ErrPageTimeout},
+	{"Page Timeout", ErrPageTimeout},
+	{"Host is down", ErrPageTimeout},
+	{"already-connected", ErrAlreadyConnected},
+	{"profile-unavailable", ErrProfileUnavailable},
+	{"Protocol not available", ErrProfileUnavailable},
+	{"not-powered", ErrNotReady},
+	{"Resource Not Ready", ErrNotReady},
+	{"busy", ErrInProgress},
+	{"Operation already in progress", ErrInProgress},
+}
+
+// hints explain what can be done about an error.
+var hints = map[error]string{
+	ErrInProgress:              "another operation on the device is still running, wait for it to finish and try again",
+	ErrAlreadyConnected:        "the device is already connected",
+	ErrAlreadyExists:           "the device is already paired, remove it first to pair it again",
+	ErrNotConnected:            "the device isn't connected, connect it first",
+	ErrAuthenticationFailed:    "the stored pairing keys may be out of date, remove the device and pair it again",
+	ErrAuthenticationCanceled:  "pairing was canceled on the device, or no agent was available to confirm it",
+	ErrAuthenticationRejected:  "the device rejected pairing, put it into pairing mode and try again",
+	ErrAuthenticationTimeout:   "pairing wasn't confirmed in time, put the device into pairing mode and try again",
+	ErrConnectionAttemptFailed: "make sure the device is switched on and in range, it may need to be paired again",
+	ErrPageTimeout:             "the device didn't respond, make sure it is switched on, in range and not connected to another host",
+	ErrProfileUnavailable:      "no profile of the device is available on this machine, for audio devices make sure the sound server is running",
+	ErrNotReady:                "the adapter isn't powered, run 'sluez adapter power on'",
+	ErrDoesNotExist:            "bluez doesn't know the device, run 'sluez pair' first or check the --adapter",
+	ErrNotSupported:            "the device or adapter doesn't support this, or bluez is too old",
+	ErrAccessDenied:            "permission denied, check the dbus policy for org.bluez allows this user",
+	ErrNotRunning:              "start bluetoothd, ie: 'systemctl start bluetooth'",
+}
+
+// Error is an error reply from bluez. It matches one of the Err errors
+// above with errors.Is.
+type Error struct {
+	// Name is the dbus error name, ie: "org.bluez.Error.InProgress".
+	Name    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" || e.Message == e.Err.Error() {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Message
+}
+
+// Unwrap returns the matching Err error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns a suggestion for fixing the error, or "" if there is none.
+func (e *Error) Hint() string {
+	return hints[e.Err]
+}
+
+// ConvertError converts dbus error replies into an *Error, any other
+// error is returned unchanged.
+func ConvertError(err error) error {
+	var name string
+	var body []interface{}
+	switch e := err.(type) {
+	case dbus.Error:
+		name, body = e.Name, e.Body
+	case *dbus.Error:
+		name, body = e.Name, e.Body
+	default:
+		return err
+	}
+	message := ""
+	if len(body) > 0 {
+		message, _ = body[0].(string)
+	}
+	kind, ok := errorNames[name]
+	if !ok {
+		if !strings.HasPrefix(name, "org.bluez.") {
+			return err
+		}
+		kind = ErrFailed
+	}
+	if kind == ErrFailed || kind == ErrConnectionAttemptFailed || kind == ErrNotAvailable {
+		for _, m := range failedMessages {
+			if strings.Contains(message, m.contains) {
+				kind = m.err
+				break
+			}
+		}
+	}
+	return &Error{Name: name, Message: message, Err: kind}
+}
+
+// Hint returns the hint of the first *Error in the causes of err, see
+// github.com/pkg/errors, or "" if there is none.
+func Hint(err error) string {
+	for err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			return e.Hint()
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return ""
+		}
+		err = cause.Cause()
+	}
+	return ""
+}
+
+// Is reports whether any error in the causes of err, see
+// github.com/pkg/errors, matches target. It is errors.Is for errors that
+// have been wrapped with errors.Wrap.
+func Is(err, target error) bool {
+	for err != nil {
+		if errors.Is(err, target) {
+			return true
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package bluez
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	monitorManagerInterface = "org.bluez.AdvertisementMonitorManager1"
+	monitorInterface        = "org.bluez.AdvertisementMonitor1"
+
+	// MonitorRSSIUnset is the RSSI threshold value bluez treats as unset.
+	MonitorRSSIUnset int16 = 127
+)
+
+// MonitorPattern matches advertisement data, Content must be found at
+// Start in the advertising data of ADType, ie: 0xff for manufacturer data.
+type MonitorPattern struct {
+	Start   byte
+	ADType  byte
+	Content []byte
+}
+
+// ParseMonitorPattern parses a pattern in the form "START:ADTYPE:HEX", ie:
+// "0:0xff:4c00" matches manufacturer data starting with Apple's company id.
+// START and ADTYPE are decimal unless prefixed with "0x".
+func ParseMonitorPattern(s string) (MonitorPattern, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return MonitorPattern{}, fmt.Errorf("%q is an invalid pattern, expected START:ADTYPE:HEX", s)
+	}
+	start, err := parseByte(parts[0])
+	if err != nil {
+		return MonitorPattern{}, fmt.Errorf("%q is an invalid pattern start: %v", parts[0], err)
+	}
+	adType, err := parseByte(parts[1])
+	if err != nil {
+		return MonitorPattern{}, fmt.Errorf("%q is an invalid advertising data type: %v", parts[1], err)
+	}
+	content, err := hex.DecodeString(strings.TrimPrefix(parts[2], "0x"))
+	if err != nil || len(content) == 0 {
+		return MonitorPattern{}, fmt.Errorf("%q is an invalid pattern content, expected hex bytes", parts[2])
+	}
+	return MonitorPattern{Start: start, ADType: adType, Content: content}, nil
+}
+
+// parseByte parses a decimal byte, or a hex byte with a "0x" prefix. A
+// leading 0 is still decimal rather than octal.
+func parseByte(s string) (byte, error) {
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	v, err := strconv.ParseUint(s, base, 8)
+	return byte(v), err
+}
+
+// MonitorOptions configures an advertisement monitor. A device is found
+// once its RSSI stays above RSSIHighThreshold for RSSIHighTimeout seconds,
+// and lost once it stays below RSSILowThreshold for RSSILowTimeout seconds.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/advertisement-monitor-api.txt
+type MonitorOptions struct {
+	Patterns          []MonitorPattern
+	RSSIHighThreshold int16
+	RSSILowThreshold  int16
+	RSSIHighTimeout   uint16
+	RSSILowTimeout    uint16
+	// RSSISamplingPeriod is in units of 100ms, 0 reports every
+	// advertisement and nil leaves it to bluez.
+	RSSISamplingPeriod *uint16
+}
+
+func (o MonitorOptions) properties() map[string]dbus.Variant {
+	props := map[string]dbus.Variant{
+		"Type":     dbus.MakeVariant("or_patterns"),
+		"Patterns": dbus.MakeVariant(o.Patterns),
+	}
+	if o.RSSIHighThreshold != MonitorRSSIUnset {
+		props["RSSIHighThreshold"] = dbus.MakeVariant(o.RSSIHighThreshold)
+	}
+	if o.RSSILowThreshold != MonitorRSSIUnset {
+		props["RSSILowThreshold"] = dbus.MakeVariant(o.RSSILowThreshold)
+	}
+	if o.RSSIHighTimeout > 0 {
+		props["RSSIHighTimeout"] = dbus.MakeVariant(o.RSSIHighTimeout)
+	}
+	if o.RSSILowTimeout > 0 {
+		props["RSSILowTimeout"] = dbus.MakeVariant(o.RSSILowTimeout)
+	}
+	if o.RSSISamplingPeriod != nil {
+		props["RSSISamplingPeriod"] = dbus.MakeVariant(*o.RSSISamplingPeriod)
+	}
+	return props
+}
+
+// MonitorEvent is sent when a monitored device is found or lost.
+type MonitorEvent struct {
+	Device dbus.ObjectPath
+	Found  bool
+}
+
+// AdvertisementMonitor passively watches for advertisements matching its
+// patterns, which uses far less power than discovery. Its exported methods
+// are called by bluez over dbus.
+type AdvertisementMonitor struct {
+	b       *Bluez
+	adapter string
+	root    dbus.ObjectPath
+	path    dbus.ObjectPath
+	props   map[string]dbus.Variant
+	events  chan MonitorEvent
+}
+
+// monitorApp is the object manager bluez reads monitors from.
+type monitorApp struct {
+	m *AdvertisementMonitor
+}
+
+// GetManagedObjects returns the monitor registered under the application.
+func (a *monitorApp) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	return map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		a.m.path: {monitorInterface: a.m.props},
+	}, nil
+}
+
+// monitorProperties exposes the monitor properties over
+// org.freedesktop.DBus.Properties.
+type monitorProperties struct {
+	m *AdvertisementMonitor
+}
+
+func (p *monitorProperties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	v, ok := p.m.props[name]
+	if iface != monitorInterface || !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"no such property " + name})
+	}
+	return v, nil
+}
+
+func (p *monitorProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != monitorInterface {
+		return map[string]dbus.Variant{}, nil
+	}
+	return p.m.props, nil
+}
+
+func (p *monitorProperties) Set(iface, name string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{name + " is read only"})
+}
+
+// RegisterAdvertisementMonitor exports a monitor application at root and
+// registers it with the adapter.
+func (b *Bluez) RegisterAdvertisementMonitor(adapterName string, root dbus.ObjectPath, options MonitorOptions) (*AdvertisementMonitor, error) {
+	if len(options.Patterns) == 0 {
+		return nil, fmt.Errorf("at least one pattern is required")
+	}
+	m := &AdvertisementMonitor{
+		b:       b,
+		adapter: adapterName,
+		root:    root,
+		path:    root + "/monitor0",
+		props:   options.properties(),
+		events:  make(chan MonitorEvent, 16),
+	}
+	if err := b.conn.Export(&monitorApp{m}, root, "org.freedesktop.DBus.ObjectManager"); err != nil {
+		return nil, err
+	}
+	if err := b.conn.Export(m, m.path, monitorInterface); err != nil {
+		m.unexport()
+		return nil, err
+	}
+	if err := b.conn.Export(&monitorProperties{m}, m.path, "org.freedesktop.DBus.Properties"); err != nil {
+		m.unexport()
+		return nil, err
+	}
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	if err := b.conn.Object(dbusBluetoothPath, path).Call(monitorManagerInterface+".RegisterMonitor", 0, root).Store(); err != nil {
+		m.unexport()
+		return nil, ConvertError(err)
+	}
+	return m, nil
+}
+
+// Events returns the channel found and lost devices are delivered on.
+func (m *AdvertisementMonitor) Events() <-chan MonitorEvent {
+	return m.events
+}
+
+// Unregister unregisters the monitor from the adapter.
+func (m *AdvertisementMonitor) Unregister() error {
+	defer m.unexport()
+	path := dbus.ObjectPath("/org/bluez/" + m.adapter)
+	return ConvertError(m.b.conn.Object(dbusBluetoothPath, path).Call(monitorManagerInterface+".UnregisterMonitor", 0, m.root).Store())
+}
+
+func (m *AdvertisementMonitor) unexport() {
+	m.b.conn.Export(nil, m.root, "org.freedesktop.DBus.ObjectManager")
+	m.b.conn.Export(nil, m.path, monitorInterface)
+	m.b.conn.Export(nil, m.path, "org.freedesktop.DBus.Properties")
+}
+
+// Release is called by bluez when the monitor is removed.
+func (m *AdvertisementMonitor) Release() *dbus.Error {
+	return nil
+}
+
+// Activate is called by bluez once the monitor is active.
+func (m *AdvertisementMonitor) Activate() *dbus.Error {
+	return nil
+}
+
+// DeviceFound is called by bluez when a device matches the monitor.
+func (m *AdvertisementMonitor) DeviceFound(device dbus.ObjectPath) *dbus.Error {
+	m.events <- MonitorEvent{Device: device, Found: true}
+	return nil
+}
+
+// DeviceLost is called by bluez when a matched device is no longer seen.
+func (m *AdvertisementMonitor) DeviceLost(device dbus.ObjectPath) *dbus.Error {
+	m.events <- MonitorEvent{Device: device, Found: false}
+	return nil
+}
+
+// DeviceAt returns the device at a bluez object path, as given in a
+// MonitorEvent.
+func (b *Bluez) DeviceAt(path dbus.ObjectPath) (Device, error) {
+	result := make(map[string]dbus.Variant)
+	if err := b.conn.Object(dbusBluetoothPath, path).Call("org.freedesktop.DBus.Properties.GetAll", 0, "org.bluez.Device1").Store(&result); err != nil {
+		return Device{}, ConvertError(err)
+	}
+	devices := b.ConvertToDevices(string(path), map[string]map[string]dbus.Variant{"org.bluez.Device1": result})
+	return devices[0], nil
+}
@@ -0,0 +1,65 @@
+package bluez
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMonitorPattern(t *testing.T) {
+	tests := []struct {
+		in   string
+		want MonitorPattern
+	}{
+		{"0:0xff:4c00", MonitorPattern{Start: 0, ADType: 0xff, Content: []byte{0x4c, 0x00}}},
+		{"2:22:0x0d18", MonitorPattern{Start: 2, ADType: 0x16, Content: []byte{0x0d, 0x18}}},
+		{"0x1f:0x09:4A424C", MonitorPattern{Start: 31, ADType: 0x09, Content: []byte("JBL")}},
+		// A leading 0 is decimal, not octal.
+		{"010:09:ff", MonitorPattern{Start: 10, ADType: 9, Content: []byte{0xff}}},
+		{"0X10:0XFF:FF", MonitorPattern{Start: 16, ADType: 0xff, Content: []byte{0xff}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseMonitorPattern(tt.in)
+		if err != nil {
+			t.Errorf("ParseMonitorPattern(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMonitorPattern(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMonitorPatternErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `"" is an invalid pattern, expected START:ADTYPE:HEX`},
+		{"0:0xff", `"0:0xff" is an invalid pattern, expected START:ADTYPE:HEX`},
+		{"0:0xff:4c:00", `"0:0xff:4c:00" is an invalid pattern, expected START:ADTYPE:HEX`},
+		{":0xff:4c00", `"" is an invalid pattern start`},
+		{"x:0xff:4c00", `"x" is an invalid pattern start`},
+		{"-1:0xff:4c00", `"-1" is an invalid pattern start`},
+		{"256:0xff:4c00", `"256" is an invalid pattern start`},
+		{"0::4c00", `"" is an invalid advertising data type`},
+		{"0:0x100:4c00", `"0x100" is an invalid advertising data type`},
+		{"0:ff:4c00", `"ff" is an invalid advertising data type`},
+		{"0:0x:4c00", `"0x" is an invalid advertising data type`},
+		{"08a:0xff:4c00", `"08a" is an invalid pattern start`},
+		{"0:0xff:", `"" is an invalid pattern content, expected hex bytes`},
+		{"0:0xff:0x", `"0x" is an invalid pattern content, expected hex bytes`},
+		{"0:0xff:4c0", `"4c0" is an invalid pattern content, expected hex bytes`},
+		{"0:0xff:zz", `"zz" is an invalid pattern content, expected hex bytes`},
+	}
+	for _, tt := range tests {
+		_, err := ParseMonitorPattern(tt.in)
+		if err == nil {
+			t.Errorf("ParseMonitorPattern(%q) succeeded, want an error", tt.in)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("ParseMonitorPattern(%q) error = %q, want %q", tt.in, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package bluez
+
+import (
+	"github.com/godbus/dbus"
+)
+
+const (
+	networkInterface       = "org.bluez.Network1"
+	networkServerInterface = "org.bluez.NetworkServer1"
+)
+
+// Network roles used for PAN connections.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/network-api.txt
+const (
+	// RoleNAP is a network access point, ie: a phone sharing its internet.
+	RoleNAP = "nap"
+	// RolePANU is a PAN user, the client of a NAP or GN.
+	RolePANU = "panu"
+	// RoleGN is a group ad-hoc network.
+	RoleGN = "gn"
+)
+
+// NetworkConnect connects to the PAN role on a device and returns the name
+// of the network interface created, ie: "bnep0".
+func (b *Bluez) NetworkConnect(adapterName, deviceMac, role string) (string, error) {
+	var iface string
+	path := b.devicePath(adapterName, deviceMac)
+	err := b.conn.Object(dbusBluetoothPath, path).Call(networkInterface+".Connect", 0, role).Store(&iface)
+	return iface, ConvertError(err)
+}
+
+// NetworkDisconnect disconnects the PAN connection to a device.
+func (b *Bluez) NetworkDisconnect(adapterName, deviceMac string) error {
+	path := b.devicePath(adapterName, deviceMac)
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call(networkInterface+".Disconnect", 0).Store())
+}
+
+// RegisterNetworkServer makes the adapter accept PAN connections for a
+// role, connections are added to the bridge interface.
+func (b *Bluez) RegisterNetworkServer(adapterName, role, bridge string) error {
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call(networkServerInterface+".Register", 0, role, bridge).Store())
+}
+
+// UnregisterNetworkServer stops the adapter accepting PAN connections for
+// a role.
+func (b *Bluez) UnregisterNetworkServer(adapterName, role string) error {
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call(networkServerInterface+".Unregister", 0, role).Store())
+}
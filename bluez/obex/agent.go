@@ -0,0 +1,91 @@
+package obex
+
+import (
+	"github.com/godbus/dbus"
+)
+
+const (
+	agentManagerInterface = "org.bluez.obex.AgentManager1"
+	agentInterface        = "org.bluez.obex.Agent1"
+	sessionInterface      = "org.bluez.obex.Session1"
+
+	errRejected = "org.bluez.obex.Error.Rejected"
+)
+
+// PushRequest is an incoming object push waiting to be authorized.
+type PushRequest struct {
+	Transfer dbus.ObjectPath
+	Sender   string
+	Name     string
+	Type     string
+	Size     uint64
+}
+
+// AuthorizeFunc decides whether to accept an incoming object push. The
+// returned filename is where obexd will write the object, returning an
+// error rejects the push.
+type AuthorizeFunc func(req PushRequest) (filename string, err error)
+
+// Agent is registered with obexd to authorize incoming object pushes. Its
+// exported methods are called by obexd over dbus.
+type Agent struct {
+	c         *Client
+	path      dbus.ObjectPath
+	authorize AuthorizeFunc
+}
+
+// RegisterAgent exports an agent at path and registers it with obexd, only
+// one agent can be registered with obexd at a time.
+func (c *Client) RegisterAgent(path dbus.ObjectPath, authorize AuthorizeFunc) (*Agent, error) {
+	a := &Agent{c: c, path: path, authorize: authorize}
+	if err := c.conn.Export(a, path, agentInterface); err != nil {
+		return nil, err
+	}
+	if err := c.conn.Object(dbusObexPath, dbusObexObjectPath).Call(agentManagerInterface+".RegisterAgent", 0, path).Store(); err != nil {
+		c.conn.Export(nil, path, agentInterface)
+		return nil, err
+	}
+	return a, nil
+}
+
+// Unregister unregisters the agent from obexd.
+func (a *Agent) Unregister() error {
+	defer a.c.conn.Export(nil, a.path, agentInterface)
+	return a.c.conn.Object(dbusObexPath, dbusObexObjectPath).Call(agentManagerInterface+".UnregisterAgent", 0, a.path).Store()
+}
+
+// Release is called by obexd when the agent is unregistered.
+func (a *Agent) Release() *dbus.Error {
+	return nil
+}
+
+// AuthorizePush is called by obexd when a device pushes an object.
+func (a *Agent) AuthorizePush(transfer dbus.ObjectPath) (string, *dbus.Error) {
+	req := PushRequest{Transfer: transfer}
+
+	props := map[string]dbus.Variant{}
+	if err := a.c.conn.Object(dbusObexPath, transfer).Call("org.freedesktop.DBus.Properties.GetAll", 0, transferInterface).Store(&props); err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	req.Name, _ = props["Name"].Value().(string)
+	req.Type, _ = props["Type"].Value().(string)
+	req.Size, _ = props["Size"].Value().(uint64)
+
+	if session, ok := props["Session"].Value().(dbus.ObjectPath); ok {
+		if v, err := a.c.conn.Object(dbusObexPath, session).GetProperty(sessionInterface + ".Destination"); err == nil {
+			req.Sender, _ = v.Value().(string)
+		}
+	}
+
+	filename, err := a.authorize(req)
+	if err != nil {
+		return "", dbus.NewError(errRejected, []interface{}{err.Error()})
+	}
+	return filename, nil
+}
+
+// Cancel is called by obexd when a request is cancelled before the agent
+// replied.
+func (a *Agent) Cancel() *dbus.Error {
+	return nil
+}
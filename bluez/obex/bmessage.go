@@ -0,0 +1,148 @@
+package obex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// BMessage is a message in the bMessage format used by map.
+type BMessage struct {
+	Status     string  `json:"status,omitempty"`
+	Type       string  `json:"type,omitempty"`
+	Folder     string  `json:"folder,omitempty"`
+	Originator []VCard `json:"originator,omitempty"`
+	Recipients []VCard `json:"recipients,omitempty"`
+	Charset    string  `json:"charset,omitempty"`
+	Body       string  `json:"body"`
+}
+
+// ParseBMessage parses a bMessage, the originator and recipients are
+// parsed as vCards and the body is returned as plain text.
+func ParseBMessage(data []byte) (BMessage, error) {
+	m := BMessage{}
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	depth := []string{}
+	var (
+		vcard []string
+		body  []string
+	)
+	for _, line := range lines {
+		in := ""
+		if len(depth) > 0 {
+			in = depth[len(depth)-1]
+		}
+		// The message body is opaque, only END:MSG ends it.
+		if in == "MSG" && line != "END:MSG" {
+			body = append(body, line)
+			continue
+		}
+		if in == "VCARD" {
+			vcard = append(vcard, line)
+		}
+
+		switch {
+		case strings.HasPrefix(line, "BEGIN:"):
+			section := strings.TrimPrefix(line, "BEGIN:")
+			depth = append(depth, section)
+			if section == "VCARD" {
+				vcard = []string{line}
+			}
+		case strings.HasPrefix(line, "END:"):
+			if len(depth) == 0 {
+				return m, fmt.Errorf("unexpected %q", line)
+			}
+			section := depth[len(depth)-1]
+			depth = depth[:len(depth)-1]
+			if section != "VCARD" {
+				continue
+			}
+			cards := ParseVCards([]byte(strings.Join(vcard, "\n")))
+			if containsString(depth, "BENV") {
+				m.Recipients = append(m.Recipients, cards...)
+			} else {
+				m.Originator = append(m.Originator, cards...)
+			}
+		case in == "BMSG" && strings.HasPrefix(line, "STATUS:"):
+			m.Status = strings.TrimPrefix(line, "STATUS:")
+		case in == "BMSG" && strings.HasPrefix(line, "TYPE:"):
+			m.Type = strings.TrimPrefix(line, "TYPE:")
+		case in == "BMSG" && strings.HasPrefix(line, "FOLDER:"):
+			m.Folder = strings.TrimPrefix(line, "FOLDER:")
+		case in == "BBODY" && strings.HasPrefix(line, "CHARSET:"):
+			m.Charset = strings.TrimPrefix(line, "CHARSET:")
+		}
+	}
+	if len(depth) != 0 {
+		return m, fmt.Errorf("bmessage is missing END:%s", depth[len(depth)-1])
+	}
+	m.Body = strings.Join(body, "\n")
+	return m, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSMS returns a bMessage for sending an sms to a phone number.
+func NewSMS(to, text string) BMessage {
+	return BMessage{
+		Status:     "READ",
+		Type:       "SMS_GSM",
+		Folder:     "telecom/msg/outbox",
+		Recipients: []VCard{{Telephones: []string{to}}},
+		Charset:    "UTF-8",
+		Body:       text,
+	}
+}
+
+// Marshal encodes the message in the bMessage format.
+func (m BMessage) Marshal() []byte {
+	var buf bytes.Buffer
+	w := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format+"\r\n", args...)
+	}
+	writeVCard := func(v VCard) {
+		w("BEGIN:VCARD")
+		w("VERSION:2.1")
+		w("N:%s", v.FormattedName)
+		if v.FormattedName != "" {
+			w("FN:%s", v.FormattedName)
+		}
+		for _, tel := range v.Telephones {
+			w("TEL:%s", tel)
+		}
+		for _, email := range v.Emails {
+			w("EMAIL:%s", email)
+		}
+		w("END:VCARD")
+	}
+
+	w("BEGIN:BMSG")
+	w("VERSION:1.0")
+	w("STATUS:%s", m.Status)
+	w("TYPE:%s", m.Type)
+	w("FOLDER:%s", m.Folder)
+	for _, v := range m.Originator {
+		writeVCard(v)
+	}
+	w("BEGIN:BENV")
+	for _, v := range m.Recipients {
+		writeVCard(v)
+	}
+	w("BEGIN:BBODY")
+	w("CHARSET:%s", m.Charset)
+	// The length covers everything from BEGIN:MSG to END:MSG inclusive.
+	msg := "BEGIN:MSG\r\n" + strings.Replace(m.Body, "\n", "\r\n", -1) + "\r\nEND:MSG\r\n"
+	w("LENGTH:%d", len(msg))
+	buf.WriteString(msg)
+	w("END:BBODY")
+	w("END:BENV")
+	w("END:BMSG")
+	return buf.Bytes()
+}
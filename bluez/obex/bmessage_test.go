@@ -0,0 +1,91 @@
+package obex
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   BMessage
+	}{
+		{
+			name: "sms",
+			in:   NewSMS("+61400000000", "running late"),
+		},
+		{
+			name: "originator and multi line body",
+			in: BMessage{
+				Status:     "UNREAD",
+				Type:       "SMS_GSM",
+				Folder:     "telecom/msg/inbox",
+				Originator: []VCard{{FormattedName: "Alice", Telephones: []string{"+61400000001"}}},
+				Recipients: []VCard{{Telephones: []string{"+61400000000"}}, {Emails: []string{"bob@example.com"}}},
+				Charset:    "UTF-8",
+				Body:       "line one\nBEGIN:VCARD\nEND:BBODY\nline four",
+			},
+		},
+		{
+			name: "empty body",
+			in:   BMessage{Status: "READ", Type: "MMS", Folder: "telecom/msg/sent", Charset: "UTF-8"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseBMessage(tt.in.Marshal())
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		// Parsed vCards also keep every property, only compare the fields.
+		for _, cards := range [][]VCard{got.Originator, got.Recipients} {
+			for i := range cards {
+				cards[i].Properties = nil
+			}
+		}
+		if !reflect.DeepEqual(got, tt.in) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.in)
+		}
+	}
+}
+
+func TestBMessageMarshalLength(t *testing.T) {
+	data := NewSMS("+61400000000", "two\nlines").Marshal()
+	i := bytes.Index(data, []byte("LENGTH:"))
+	j := bytes.Index(data, []byte("BEGIN:MSG"))
+	k := bytes.Index(data, []byte("END:MSG\r\n"))
+	if i < 0 || j < 0 || k < 0 {
+		t.Fatalf("missing LENGTH or MSG in %q", data)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(string(data[i+len("LENGTH:") : j])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := k + len("END:MSG\r\n") - j; length != want {
+		t.Errorf("LENGTH = %d, want %d", length, want)
+	}
+	if !bytes.Contains(data, []byte("BEGIN:MSG\r\ntwo\r\nlines\r\nEND:MSG\r\n")) {
+		t.Errorf("body isn't CRLF terminated: %q", data)
+	}
+}
+
+func TestParseBMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"unexpected end", "END:BMSG\r\n", `unexpected "END:BMSG"`},
+		{"missing end", "BEGIN:BMSG\r\nBEGIN:BENV\r\nEND:BENV\r\n", "bmessage is missing END:BMSG"},
+		{"unterminated body", "BEGIN:BMSG\r\nBEGIN:BENV\r\nBEGIN:BBODY\r\nBEGIN:MSG\r\ntext\r\n", "bmessage is missing END:MSG"},
+	}
+	for _, tt := range tests {
+		_, err := ParseBMessage([]byte(tt.in))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package obex
+
+import (
+	"path/filepath"
+
+	"github.com/godbus/dbus"
+)
+
+const fileTransferInterface = "org.bluez.obex.FileTransfer1"
+
+// FolderEntry is a file or folder on the remote device.
+type FolderEntry struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Size     uint64 `json:"size"`
+	Modified string `json:"modified,omitempty"`
+}
+
+// IsFolder reports whether the entry is a folder.
+func (e FolderEntry) IsFolder() bool {
+	return e.Type == "folder"
+}
+
+// ChangeFolder changes the current folder of a file transfer session, ".."
+// is the parent folder and "" is the root folder.
+func (c *Client) ChangeFolder(session Session, folder string) error {
+	return c.callSession(session, fileTransferInterface+".ChangeFolder", folder).Store()
+}
+
+// CreateFolder creates a folder in the current folder and changes into it.
+func (c *Client) CreateFolder(session Session, folder string) error {
+	return c.callSession(session, fileTransferInterface+".CreateFolder", folder).Store()
+}
+
+// ListFolder lists the contents of the current folder.
+func (c *Client) ListFolder(session Session) ([]FolderEntry, error) {
+	results := []map[string]dbus.Variant{}
+	if err := c.callSession(session, fileTransferInterface+".ListFolder").Store(&results); err != nil {
+		return nil, err
+	}
+	entries := make([]FolderEntry, 0, len(results))
+	for _, r := range results {
+		e := FolderEntry{}
+		e.Name, _ = r["Name"].Value().(string)
+		e.Type, _ = r["Type"].Value().(string)
+		e.Size, _ = r["Size"].Value().(uint64)
+		e.Modified, _ = r["Modified"].Value().(string)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// GetFile copies a file from the current folder on the device to the local
+// targetFile and waits for the transfer to finish.
+func (c *Client) GetFile(session Session, sourceFile, targetFile string, progress ProgressFunc) (Transfer, error) {
+	targetFile, err := filepath.Abs(targetFile)
+	if err != nil {
+		return Transfer{}, err
+	}
+	return c.track(func() (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			path  dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.callSession(session, fileTransferInterface+".GetFile", targetFile, sourceFile).Store(&path, &props)
+		return path, props, err
+	}, progress)
+}
+
+// PutFile copies a local file to targetFile in the current folder on the
+// device and waits for the transfer to finish.
+func (c *Client) PutFile(session Session, sourceFile, targetFile string, progress ProgressFunc) (Transfer, error) {
+	sourceFile, err := filepath.Abs(sourceFile)
+	if err != nil {
+		return Transfer{}, err
+	}
+	return c.track(func() (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			path  dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.callSession(session, fileTransferInterface+".PutFile", sourceFile, targetFile).Store(&path, &props)
+		return path, props, err
+	}, progress)
+}
+
+// Delete removes a file or empty folder from the current folder.
+func (c *Client) Delete(session Session, name string) error {
+	return c.callSession(session, fileTransferInterface+".Delete", name).Store()
+}
@@ -0,0 +1,182 @@
+package obex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	messageAccessInterface = "org.bluez.obex.MessageAccess1"
+	messageInterface       = "org.bluez.obex.Message1"
+
+	// MessageRootFolder is the folder containing the message folders,
+	// ie: "inbox", "outbox", "sent".
+	MessageRootFolder = "/telecom/msg"
+)
+
+// Message is a message listed in a map session.
+type Message struct {
+	Path             dbus.ObjectPath `json:"-"`
+	Handle           string          `json:"handle"`
+	Folder           string          `json:"folder"`
+	Type             string          `json:"type"`
+	Subject          string          `json:"subject"`
+	Timestamp        string          `json:"timestamp"`
+	Sender           string          `json:"sender"`
+	SenderAddress    string          `json:"sender_address"`
+	Recipient        string          `json:"recipient"`
+	RecipientAddress string          `json:"recipient_address"`
+	Size             uint64          `json:"size"`
+	Status           string          `json:"status"`
+	Read             bool            `json:"read"`
+	Sent             bool            `json:"sent"`
+}
+
+// MessageFilter limits the messages returned by ListMessages.
+type MessageFilter struct {
+	Offset   uint16
+	MaxCount uint16
+	// Types limits the message types, ie: "sms", "email" or "mms".
+	Types []string
+	// PeriodBegin and PeriodEnd limit messages to a time period, the
+	// format is "YYYYMMDDTHHMMSS".
+	PeriodBegin string
+	PeriodEnd   string
+	// UnreadOnly only returns messages that haven't been read.
+	UnreadOnly bool
+	Sender     string
+	Recipient  string
+}
+
+func (f MessageFilter) dbusFilters() map[string]dbus.Variant {
+	filters := map[string]dbus.Variant{}
+	if f.Offset > 0 {
+		filters["Offset"] = dbus.MakeVariant(f.Offset)
+	}
+	if f.MaxCount > 0 {
+		filters["MaxCount"] = dbus.MakeVariant(f.MaxCount)
+	}
+	if len(f.Types) > 0 {
+		filters["Types"] = dbus.MakeVariant(f.Types)
+	}
+	if f.PeriodBegin != "" {
+		filters["PeriodBegin"] = dbus.MakeVariant(f.PeriodBegin)
+	}
+	if f.PeriodEnd != "" {
+		filters["PeriodEnd"] = dbus.MakeVariant(f.PeriodEnd)
+	}
+	if f.UnreadOnly {
+		filters["Read"] = dbus.MakeVariant(false)
+	}
+	if f.Sender != "" {
+		filters["Sender"] = dbus.MakeVariant(f.Sender)
+	}
+	if f.Recipient != "" {
+		filters["Recipient"] = dbus.MakeVariant(f.Recipient)
+	}
+	return filters
+}
+
+// SetMessageFolder changes the current folder of a map session, the folder
+// may contain multiple levels, ie: "/telecom/msg".
+func (c *Client) SetMessageFolder(session Session, folder string) error {
+	return c.callSession(session, messageAccessInterface+".SetFolder", folder).Store()
+}
+
+// ListMessageFolders lists the folders in the current folder.
+func (c *Client) ListMessageFolders(session Session) ([]string, error) {
+	results := []map[string]dbus.Variant{}
+	if err := c.callSession(session, messageAccessInterface+".ListFolders", map[string]dbus.Variant{}).Store(&results); err != nil {
+		return nil, err
+	}
+	folders := make([]string, 0, len(results))
+	for _, r := range results {
+		if name, ok := r["Name"].Value().(string); ok {
+			folders = append(folders, name)
+		}
+	}
+	return folders, nil
+}
+
+// ListMessages lists the messages in a subfolder of the current folder.
+// Listing messages is also what creates the message objects needed by
+// GetMessage.
+func (c *Client) ListMessages(session Session, folder string, filter MessageFilter) ([]Message, error) {
+	results := map[dbus.ObjectPath]map[string]dbus.Variant{}
+	if err := c.callSession(session, messageAccessInterface+".ListMessages", folder, filter.dbusFilters()).Store(&results); err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(results))
+	for p, props := range results {
+		m := Message{
+			Path:   p,
+			Handle: strings.TrimPrefix(path.Base(string(p)), "message"),
+		}
+		m.Folder, _ = props["Folder"].Value().(string)
+		m.Type, _ = props["Type"].Value().(string)
+		m.Subject, _ = props["Subject"].Value().(string)
+		m.Timestamp, _ = props["Timestamp"].Value().(string)
+		m.Sender, _ = props["Sender"].Value().(string)
+		m.SenderAddress, _ = props["SenderAddress"].Value().(string)
+		m.Recipient, _ = props["Recipient"].Value().(string)
+		m.RecipientAddress, _ = props["RecipientAddress"].Value().(string)
+		m.Size, _ = props["Size"].Value().(uint64)
+		m.Status, _ = props["Status"].Value().(string)
+		m.Read, _ = props["Read"].Value().(bool)
+		m.Sent, _ = props["Sent"].Value().(bool)
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// GetMessage downloads a listed message as a bMessage.
+func (c *Client) GetMessage(message Message, attachments bool, progress ProgressFunc) (BMessage, error) {
+	data, err := c.pullToFile(func(target string) (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			p     dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.conn.Object(dbusObexPath, message.Path).Call(messageInterface+".Get", 0, target, attachments).Store(&p, &props)
+		return p, props, err
+	}, progress)
+	if err != nil {
+		return BMessage{}, err
+	}
+	return ParseBMessage(data)
+}
+
+// PushMessage sends a message to the device for delivery, folder is
+// usually "outbox" from MessageRootFolder.
+func (c *Client) PushMessage(session Session, folder string, message BMessage, progress ProgressFunc) error {
+	tmp, err := ioutil.TempFile("", "sluez-bmessage-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(message.Marshal()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	args := map[string]dbus.Variant{"Charset": dbus.MakeVariant("utf8")}
+	_, err = c.track(func() (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			p     dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.callSession(session, messageAccessInterface+".PushMessage", tmp.Name(), folder, args).Store(&p, &props)
+		return p, props, err
+	}, progress)
+	if err != nil {
+		return fmt.Errorf("unable to push message: %v", err)
+	}
+	return nil
+}
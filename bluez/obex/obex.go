@@ -0,0 +1,78 @@
+// Package obex interacts with the bluez obex daemon (obexd) over the dbus
+// session bus, which is used to transfer files and other objects to and
+// from bluetooth devices.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/obex-api.txt
+package obex
+
+import (
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	dbusObexPath       = "org.bluez.obex"
+	dbusObexObjectPath = dbus.ObjectPath("/org/bluez/obex")
+	clientInterface    = "org.bluez.obex.Client1"
+)
+
+// Session targets, each target is a different obex profile.
+const (
+	TargetObjectPush   = "opp"
+	TargetFileTransfer = "ftp"
+	TargetPhonebook    = "pbap"
+	TargetMessage      = "map"
+)
+
+// Client is used to create obex sessions with bluetooth devices. Unlike
+// the rest of bluez, obexd runs on the session bus.
+type Client struct {
+	// Stop cancels the transfer in progress when it is closed.
+	Stop <-chan struct{}
+	// StallTimeout cancels a transfer that reports no progress for this
+	// long, 0 waits forever.
+	StallTimeout time.Duration
+
+	conn *dbus.Conn
+}
+
+// NewClient returns a new Client, conn must be a connection to the
+// session bus.
+func NewClient(conn *dbus.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+// Session is an obex session with a bluetooth device for a single target.
+type Session struct {
+	Path        dbus.ObjectPath
+	Destination string
+	Target      string
+}
+
+// CreateSession creates a session with the device at the destination mac
+// address. Source is the address of the adapter to use, an empty source
+// lets obexd choose the adapter.
+func (c *Client) CreateSession(destination, source, target string) (Session, error) {
+	args := map[string]dbus.Variant{
+		"Target": dbus.MakeVariant(target),
+	}
+	if source != "" {
+		args["Source"] = dbus.MakeVariant(source)
+	}
+	var path dbus.ObjectPath
+	if err := c.conn.Object(dbusObexPath, dbusObexObjectPath).Call(clientInterface+".CreateSession", 0, destination, args).Store(&path); err != nil {
+		return Session{}, err
+	}
+	return Session{Path: path, Destination: destination, Target: target}, nil
+}
+
+// RemoveSession closes a session, any transfers still in progress are
+// cancelled.
+func (c *Client) RemoveSession(session Session) error {
+	return c.conn.Object(dbusObexPath, dbusObexObjectPath).Call(clientInterface+".RemoveSession", 0, session.Path).Store()
+}
+
+// callSession is used to interact with an interface on a session.
+func (c *Client) callSession(session Session, method string, args ...interface{}) *dbus.Call {
+	return c.conn.Object(dbusObexPath, session.Path).Call(method, 0, args...)
+}
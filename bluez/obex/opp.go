@@ -0,0 +1,26 @@
+package obex
+
+import (
+	"path/filepath"
+
+	"github.com/godbus/dbus"
+)
+
+const objectPushInterface = "org.bluez.obex.ObjectPush1"
+
+// SendFile pushes a local file to the device in an object push session and
+// waits for the transfer to finish.
+func (c *Client) SendFile(session Session, sourceFile string, progress ProgressFunc) (Transfer, error) {
+	sourceFile, err := filepath.Abs(sourceFile)
+	if err != nil {
+		return Transfer{}, err
+	}
+	return c.track(func() (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			path  dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.callSession(session, objectPushInterface+".SendFile", sourceFile).Store(&path, &props)
+		return path, props, err
+	}, progress)
+}
@@ -0,0 +1,104 @@
+package obex
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/godbus/dbus"
+)
+
+const phonebookInterface = "org.bluez.obex.PhonebookAccess1"
+
+// Phonebooks that can be selected with SelectPhonebook.
+const (
+	PhonebookContacts = "pb"
+	PhonebookIncoming = "ich"
+	PhonebookOutgoing = "och"
+	PhonebookMissed   = "mch"
+	PhonebookCombined = "cch"
+)
+
+// Phonebook locations, "sim1" is the first SIM card.
+const (
+	LocationInternal = "int"
+	LocationSIM      = "sim1"
+)
+
+// PhonebookFilter limits and formats the vCards returned by PullAll.
+type PhonebookFilter struct {
+	// Format is either "vcard21" or "vcard30", empty uses the device
+	// default.
+	Format string
+	// Order is one of "indexed", "alphanumeric" or "phonetic".
+	Order string
+	// Offset is the index of the first vCard to return.
+	Offset uint16
+	// MaxCount is the largest number of vCards to return, 0 uses the
+	// device default (which is usually all of them).
+	MaxCount uint16
+	// Fields limits the vCard fields returned, ie: "FN", "TEL".
+	Fields []string
+}
+
+func (f PhonebookFilter) dbusFilters() map[string]dbus.Variant {
+	filters := map[string]dbus.Variant{}
+	if f.Format != "" {
+		filters["Format"] = dbus.MakeVariant(f.Format)
+	}
+	if f.Order != "" {
+		filters["Order"] = dbus.MakeVariant(f.Order)
+	}
+	if f.Offset > 0 {
+		filters["Offset"] = dbus.MakeVariant(f.Offset)
+	}
+	if f.MaxCount > 0 {
+		filters["MaxCount"] = dbus.MakeVariant(f.MaxCount)
+	}
+	if len(f.Fields) > 0 {
+		filters["Fields"] = dbus.MakeVariant(f.Fields)
+	}
+	return filters
+}
+
+// SelectPhonebook selects the phonebook used by later calls in a pbap
+// session.
+func (c *Client) SelectPhonebook(session Session, location, phonebook string) error {
+	return c.callSession(session, phonebookInterface+".Select", location, phonebook).Store()
+}
+
+// PhonebookSize returns the number of entries in the selected phonebook.
+func (c *Client) PhonebookSize(session Session) (uint16, error) {
+	var size uint16
+	err := c.callSession(session, phonebookInterface+".GetSize").Store(&size)
+	return size, err
+}
+
+// PullAll downloads the vCards in the selected phonebook.
+func (c *Client) PullAll(session Session, filter PhonebookFilter, progress ProgressFunc) ([]byte, error) {
+	return c.pullToFile(func(target string) (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		var (
+			path  dbus.ObjectPath
+			props map[string]dbus.Variant
+		)
+		err := c.callSession(session, phonebookInterface+".PullAll", target, filter.dbusFilters()).Store(&path, &props)
+		return path, props, err
+	}, progress)
+}
+
+// pullToFile runs a transfer that obexd writes to a temporary file, and
+// returns the contents of the file once the transfer completes.
+func (c *Client) pullToFile(start func(target string) (dbus.ObjectPath, map[string]dbus.Variant, error), progress ProgressFunc) ([]byte, error) {
+	tmp, err := ioutil.TempFile("", "sluez-obex-")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if _, err := c.track(func() (dbus.ObjectPath, map[string]dbus.Variant, error) {
+		return start(tmp.Name())
+	}, progress); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(tmp.Name())
+}
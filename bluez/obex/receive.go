@@ -0,0 +1,270 @@
+package obex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// ReceivePolicy decides which incoming object pushes are accepted.
+type ReceivePolicy struct {
+	// AllowedSenders is the list of device addresses allowed to push
+	// objects, an empty list allows any device.
+	AllowedSenders []string
+	// MaxSize is the largest object accepted in bytes, 0 is no limit. It
+	// is checked against the size the sender declares and again against
+	// the size received, as the declared size can be missing.
+	MaxSize uint64
+	// AllowedExtensions is the list of file extensions accepted, ie:
+	// ".jpg", an empty list allows any extension.
+	AllowedExtensions []string
+}
+
+// Check returns an error explaining why a push request is rejected, nil
+// is returned if the request is allowed.
+func (p ReceivePolicy) Check(req PushRequest) error {
+	if len(p.AllowedSenders) > 0 && !containsFold(p.AllowedSenders, req.Sender) {
+		return fmt.Errorf("sender %q is not allowed", req.Sender)
+	}
+	if p.MaxSize > 0 && req.Size > p.MaxSize {
+		return fmt.Errorf("size %d is larger than the maximum %d", req.Size, p.MaxSize)
+	}
+	if len(p.AllowedExtensions) > 0 {
+		ext := filepath.Ext(req.Name)
+		if ext == "" || !containsFold(p.AllowedExtensions, ext) && !containsFold(p.AllowedExtensions, strings.TrimPrefix(ext, ".")) {
+			return fmt.Errorf("extension %q is not allowed", ext)
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Receive statuses used in ReceiveRecord.
+const (
+	ReceiveRejected = "rejected"
+	ReceiveComplete = "complete"
+	ReceiveFailed   = "failed"
+)
+
+// ReceiveRecord describes the outcome of an incoming object push.
+type ReceiveRecord struct {
+	Time   time.Time `json:"time"`
+	Sender string    `json:"sender"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type,omitempty"`
+	Size   uint64    `json:"size"`
+	Path   string    `json:"path,omitempty"`
+	Status string    `json:"status"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Receiver accepts object pushes allowed by its policy into a directory.
+// Objects are written to a temporary file which is only renamed to its
+// final name once the transfer completes, so partial files are never seen.
+type Receiver struct {
+	c      *Client
+	dir    string
+	policy ReceivePolicy
+
+	mu      sync.Mutex
+	pending map[dbus.ObjectPath]pendingReceive
+
+	// recordMu serialises calls to record, which is called from the agent
+	// as well as from Run.
+	recordMu sync.Mutex
+}
+
+type pendingReceive struct {
+	req     PushRequest
+	tmpPath string
+}
+
+// NewReceiver returns a Receiver writing objects into dir.
+func (c *Client) NewReceiver(dir string, policy ReceivePolicy) *Receiver {
+	return &Receiver{
+		c:       c,
+		dir:     dir,
+		policy:  policy,
+		pending: map[dbus.ObjectPath]pendingReceive{},
+	}
+}
+
+// Run registers the receiver as the obexd agent and handles incoming
+// pushes until stop is closed. Every accepted or rejected push is passed
+// to record, one at a time.
+func (r *Receiver) Run(agentPath dbus.ObjectPath, stop <-chan struct{}, record func(ReceiveRecord)) error {
+	unlocked := record
+	record = func(rec ReceiveRecord) {
+		r.recordMu.Lock()
+		defer r.recordMu.Unlock()
+		unlocked(rec)
+	}
+
+	match := "type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path_namespace='/org/bluez/obex'"
+	if err := r.c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store(); err != nil {
+		return err
+	}
+	defer r.c.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match)
+	ch := make(chan *dbus.Signal, 16)
+	r.c.conn.Signal(ch)
+	defer bluez.RemoveSignal(r.c.conn, ch)
+
+	agent, err := r.c.RegisterAgent(agentPath, func(req PushRequest) (string, error) {
+		return r.authorize(req, record)
+	})
+	if err != nil {
+		return err
+	}
+	defer agent.Unregister()
+
+	for {
+		select {
+		case <-stop:
+			r.abandonPending()
+			return nil
+		case signal, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("dbus connection closed")
+			}
+			r.handleSignal(signal, record)
+		}
+	}
+}
+
+func (r *Receiver) authorize(req PushRequest, record func(ReceiveRecord)) (string, error) {
+	rec := ReceiveRecord{Time: time.Now(), Sender: req.Sender, Name: req.Name, Type: req.Type, Size: req.Size}
+	name := filepath.Base(req.Name)
+	err := r.policy.Check(req)
+	if err == nil && (name == "." || name == "/" || name == "" || strings.HasPrefix(name, ".")) {
+		err = fmt.Errorf("invalid file name %q", req.Name)
+	}
+	var tmp *os.File
+	if err == nil {
+		tmp, err = ioutil.TempFile(r.dir, ".sluez-receive-")
+	}
+	if err != nil {
+		rec.Status = ReceiveRejected
+		rec.Reason = err.Error()
+		record(rec)
+		return "", err
+	}
+	// obexd creates the file itself, the temporary file is only used to
+	// reserve a unique name.
+	tmp.Close()
+
+	r.mu.Lock()
+	r.pending[req.Transfer] = pendingReceive{req: req, tmpPath: tmp.Name()}
+	r.mu.Unlock()
+	return tmp.Name(), nil
+}
+
+func (r *Receiver) handleSignal(signal *dbus.Signal, record func(ReceiveRecord)) {
+	if signal.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(signal.Body) < 2 {
+		return
+	}
+	if iface, _ := signal.Body[0].(string); iface != transferInterface {
+		return
+	}
+	changed, _ := signal.Body[1].(map[string]dbus.Variant)
+	status, _ := changed["Status"].Value().(string)
+	if status != StatusComplete && status != StatusError {
+		return
+	}
+
+	r.mu.Lock()
+	p, ok := r.pending[signal.Path]
+	delete(r.pending, signal.Path)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	rec := ReceiveRecord{Time: time.Now(), Sender: p.req.Sender, Name: p.req.Name, Type: p.req.Type, Size: p.req.Size}
+	if status == StatusError {
+		os.Remove(p.tmpPath)
+		rec.Status = ReceiveFailed
+		rec.Reason = "transfer failed"
+		record(rec)
+		return
+	}
+	// The size declared by the sender may be missing or wrong, so check
+	// what was actually received before keeping it.
+	if fi, err := os.Stat(p.tmpPath); err == nil {
+		rec.Size = uint64(fi.Size())
+	}
+	if r.policy.MaxSize > 0 && rec.Size > r.policy.MaxSize {
+		os.Remove(p.tmpPath)
+		rec.Status = ReceiveRejected
+		rec.Reason = fmt.Sprintf("size %d is larger than the maximum %d", rec.Size, r.policy.MaxSize)
+		record(rec)
+		return
+	}
+	path, err := r.moveToFinalPath(p.tmpPath, filepath.Base(p.req.Name))
+	if err != nil {
+		os.Remove(p.tmpPath)
+		rec.Status = ReceiveFailed
+		rec.Reason = err.Error()
+		record(rec)
+		return
+	}
+	rec.Status = ReceiveComplete
+	rec.Path = path
+	record(rec)
+}
+
+// moveToFinalPath moves the file at tmpPath to a path in the receive
+// directory for name, a numeric suffix is added to avoid overwriting
+// files. Each candidate is reserved by creating it exclusively before the
+// rename, so a file created in the meantime is never replaced.
+func (r *Receiver) moveToFinalPath(tmpPath, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; i < 1000; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		path := filepath.Join(r.dir, candidate)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		f.Close()
+		if err := os.Rename(tmpPath, path); err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("unable to find a free file name for %q", name)
+}
+
+// abandonPending removes the temporary files of transfers that were still
+// in progress when the receiver stopped.
+func (r *Receiver) abandonPending() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for path, p := range r.pending {
+		os.Remove(p.tmpPath)
+		delete(r.pending, path)
+	}
+}
@@ -0,0 +1,147 @@
+package obex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+const transferInterface = "org.bluez.obex.Transfer1"
+
+// Transfer statuses reported by obexd.
+const (
+	StatusQueued    = "queued"
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusComplete  = "complete"
+	StatusError     = "error"
+)
+
+// Transfer is a single object being sent or received in a session.
+type Transfer struct {
+	Path        dbus.ObjectPath
+	Name        string
+	Filename    string
+	Status      string
+	Size        uint64
+	Transferred uint64
+}
+
+// update applies transfer properties to the transfer.
+func (t *Transfer) update(props map[string]dbus.Variant) {
+	if v, ok := props["Name"].Value().(string); ok {
+		t.Name = v
+	}
+	if v, ok := props["Filename"].Value().(string); ok {
+		t.Filename = v
+	}
+	if v, ok := props["Status"].Value().(string); ok {
+		t.Status = v
+	}
+	if v, ok := props["Size"].Value().(uint64); ok {
+		t.Size = v
+	}
+	// Signals aren't guaranteed to be delivered in order, so never go
+	// backwards.
+	if v, ok := props["Transferred"].Value().(uint64); ok && v > t.Transferred {
+		t.Transferred = v
+	}
+}
+
+// ProgressFunc is called every time a transfer reports progress.
+type ProgressFunc func(t Transfer)
+
+// startFunc starts a transfer and returns its object path and initial
+// properties.
+type startFunc func() (dbus.ObjectPath, map[string]dbus.Variant, error)
+
+// track starts a transfer and waits for it to complete or fail. The
+// signal watch is registered before the transfer is started as small
+// transfers can complete before the call returns. The transfer is
+// cancelled when Stop is closed or it stalls for longer than StallTimeout.
+func (c *Client) track(start startFunc, progress ProgressFunc) (Transfer, error) {
+	match := "type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path_namespace='/org/bluez/obex'"
+	if err := c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store(); err != nil {
+		return Transfer{}, err
+	}
+	defer c.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match)
+	ch := make(chan *dbus.Signal, 16)
+	c.conn.Signal(ch)
+	defer bluez.RemoveSignal(c.conn, ch)
+
+	path, props, err := start()
+	if err != nil {
+		return Transfer{}, err
+	}
+	t := Transfer{Path: path, Status: StatusQueued}
+	t.update(props)
+	if progress != nil {
+		progress(t)
+	}
+
+	// Without a StallTimeout stalled is nil, which never fires.
+	var (
+		timer   *time.Timer
+		stalled <-chan time.Time
+	)
+	if c.StallTimeout > 0 {
+		timer = time.NewTimer(c.StallTimeout)
+		defer timer.Stop()
+		stalled = timer.C
+	}
+	for {
+		var (
+			signal *dbus.Signal
+			ok     bool
+		)
+		select {
+		case signal, ok = <-ch:
+			if !ok {
+				return t, fmt.Errorf("connection closed while waiting for transfer of %q", t.Name)
+			}
+		case <-c.Stop:
+			c.Cancel(t)
+			return t, fmt.Errorf("transfer of %q was cancelled after %d bytes", t.Name, t.Transferred)
+		case <-stalled:
+			c.Cancel(t)
+			return t, fmt.Errorf("transfer of %q stalled after %d bytes, no progress for %s", t.Name, t.Transferred, c.StallTimeout)
+		}
+		if signal.Path != path || signal.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(signal.Body) < 2 {
+			continue
+		}
+		if iface, _ := signal.Body[0].(string); iface != transferInterface {
+			continue
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		t.update(changed)
+		if timer != nil {
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(c.StallTimeout)
+		}
+		if t.Status == StatusComplete && t.Size > 0 {
+			t.Transferred = t.Size
+		}
+		if progress != nil {
+			progress(t)
+		}
+		switch t.Status {
+		case StatusComplete:
+			return t, nil
+		case StatusError:
+			return t, fmt.Errorf("transfer of %q failed after %d bytes", t.Name, t.Transferred)
+		}
+	}
+}
+
+// Cancel stops a transfer that is in progress.
+func (c *Client) Cancel(t Transfer) error {
+	return c.conn.Object(dbusObexPath, t.Path).Call(transferInterface+".Cancel", 0).Store()
+}
@@ -0,0 +1,177 @@
+package obex
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// VCardProperty is a single line of a vCard, ie:
+// "TEL;TYPE=CELL:+61 400 000 000".
+type VCardProperty struct {
+	Name   string              `json:"name"`
+	Params map[string][]string `json:"params,omitempty"`
+	Value  string              `json:"value"`
+}
+
+// VCard is a contact or call history entry downloaded from a phonebook.
+type VCard struct {
+	FormattedName string          `json:"fn,omitempty"`
+	Telephones    []string        `json:"tel,omitempty"`
+	Emails        []string        `json:"email,omitempty"`
+	CallType      string          `json:"call_type,omitempty"`
+	CallDateTime  string          `json:"call_datetime,omitempty"`
+	Properties    []VCardProperty `json:"properties"`
+}
+
+// ParseVCards parses vCard 2.1 and 3.0 data containing any number of
+// vCards. Quoted printable values are decoded.
+func ParseVCards(data []byte) []VCard {
+	cards := []VCard{}
+	var current *VCard
+	for _, line := range unfoldVCardLines(data) {
+		p, ok := parseVCardProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VCARD"):
+			current = &VCard{Properties: []VCardProperty{}}
+			continue
+		case p.Name == "END" && strings.EqualFold(p.Value, "VCARD"):
+			if current != nil {
+				cards = append(cards, *current)
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		}
+
+		switch p.Name {
+		case "FN":
+			current.FormattedName = p.Value
+		case "TEL":
+			current.Telephones = append(current.Telephones, p.Value)
+		case "EMAIL":
+			current.Emails = append(current.Emails, p.Value)
+		case "X-IRMC-CALL-DATETIME":
+			current.CallDateTime = p.Value
+			// The call type is a parameter without a name in vCard 2.1, ie:
+			// "X-IRMC-CALL-DATETIME;MISSED:20180601T100000".
+			for k, v := range p.Params {
+				if k == "TYPE" && len(v) > 0 {
+					current.CallType = v[0]
+				} else if len(v) == 0 {
+					current.CallType = k
+				}
+			}
+		}
+		current.Properties = append(current.Properties, p)
+	}
+	return cards
+}
+
+// unfoldVCardLines joins folded lines. Lines starting with whitespace
+// continue the previous line, and quoted printable values in vCard 2.1
+// continue when a line ends with "=".
+func unfoldVCardLines(data []byte) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		n := len(lines)
+		switch {
+		case n > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			lines[n-1] += line[1:]
+		case n > 0 && strings.HasSuffix(lines[n-1], "=") && strings.Contains(strings.ToUpper(lines[n-1]), "QUOTED-PRINTABLE"):
+			lines[n-1] += "\r\n" + line
+		default:
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func parseVCardProperty(line string) (VCardProperty, bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return VCardProperty{}, false
+	}
+	p := VCardProperty{Value: line[i+1:]}
+	parts := strings.Split(line[:i], ";")
+	p.Name = strings.ToUpper(parts[0])
+	// Grouped properties, ie: "item1.TEL", are treated as the property.
+	if j := strings.LastIndex(p.Name, "."); j >= 0 {
+		p.Name = p.Name[j+1:]
+	}
+
+	quotedPrintable, binary := false, false
+	for _, param := range parts[1:] {
+		if p.Params == nil {
+			p.Params = map[string][]string{}
+		}
+		kv := strings.SplitN(param, "=", 2)
+		key := strings.ToUpper(kv[0])
+		if len(kv) == 1 {
+			p.Params[key] = nil
+			if key == "QUOTED-PRINTABLE" {
+				quotedPrintable = true
+			}
+			continue
+		}
+		for _, v := range strings.Split(kv[1], ",") {
+			p.Params[key] = append(p.Params[key], v)
+		}
+		if key == "ENCODING" && strings.EqualFold(kv[1], "QUOTED-PRINTABLE") {
+			quotedPrintable = true
+		}
+		if key == "ENCODING" && (strings.EqualFold(kv[1], "B") || strings.EqualFold(kv[1], "BASE64")) {
+			binary = true
+		}
+	}
+	if quotedPrintable {
+		if decoded, err := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(p.Value))); err == nil {
+			p.Value = string(decoded)
+		}
+	}
+	if !binary {
+		p.Value = unescapeVCardText(p.Value, structuredVCardProperties[p.Name])
+	}
+	return p, true
+}
+
+// structuredVCardProperties have values made of components separated by
+// ";", an escaped ";" is kept so the components can still be split.
+var structuredVCardProperties = map[string]bool{"N": true, "ADR": true, "ORG": true}
+
+// unescapeVCardText undoes the escaping of text values, "\\", "\,", "\;"
+// and "\n" or "\N" for a line break. Unknown escapes are left as they are.
+func unescapeVCardText(value string, structured bool) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		switch c := value[i+1]; {
+		case c == 'n' || c == 'N':
+			b.WriteByte('\n')
+		case c == '\\' || c == ',' || c == ';' && !structured:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+		i++
+	}
+	return b.String()
+}
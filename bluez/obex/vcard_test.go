@@ -0,0 +1,181 @@
+package obex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnfoldVCardLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "space and tab continuations",
+			in:   "BEGIN:VCARD\r\nNOTE:a long\r\n  note\r\n\t folded\r\nEND:VCARD\r\n",
+			want: []string{"BEGIN:VCARD", "NOTE:a long note folded", "END:VCARD"},
+		},
+		{
+			name: "blank lines are dropped",
+			in:   "BEGIN:VCARD\n\nFN:Alice\n\nEND:VCARD\n",
+			want: []string{"BEGIN:VCARD", "FN:Alice", "END:VCARD"},
+		},
+		{
+			name: "quoted printable soft line breaks",
+			in:   "NOTE;ENCODING=QUOTED-PRINTABLE:first=\r\nsecond\r\nFN:Bob\r\n",
+			want: []string{"NOTE;ENCODING=QUOTED-PRINTABLE:first=\r\nsecond", "FN:Bob"},
+		},
+		{
+			name: "trailing = without quoted printable",
+			in:   "NOTE:a=\r\nFN:Bob\r\n",
+			want: []string{"NOTE:a=", "FN:Bob"},
+		},
+	}
+	for _, tt := range tests {
+		if got := unfoldVCardLines([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseVCardProperty(t *testing.T) {
+	tests := []struct {
+		line string
+		want VCardProperty
+		ok   bool
+	}{
+		{
+			line: "TEL;TYPE=CELL,VOICE:+61 400 000 000",
+			want: VCardProperty{Name: "TEL", Params: map[string][]string{"TYPE": {"CELL", "VOICE"}}, Value: "+61 400 000 000"},
+			ok:   true,
+		},
+		{
+			line: "item1.EMAIL;type=INTERNET:alice@example.com",
+			want: VCardProperty{Name: "EMAIL", Params: map[string][]string{"TYPE": {"INTERNET"}}, Value: "alice@example.com"},
+			ok:   true,
+		},
+		{
+			line: "fn:Smith\\, Alice",
+			want: VCardProperty{Name: "FN", Value: "Smith, Alice"},
+			ok:   true,
+		},
+		{
+			line: "N:Smith\\,Jr;Alice;;;",
+			want: VCardProperty{Name: "N", Value: "Smith,Jr;Alice;;;"},
+			ok:   true,
+		},
+		{
+			line: "FN:Smith\\; Jr",
+			want: VCardProperty{Name: "FN", Value: "Smith; Jr"},
+			ok:   true,
+		},
+		{
+			line: "NOTE:first\\nsecond\\Nthird\\, \\\\ and \\;",
+			want: VCardProperty{Name: "NOTE", Value: "first\nsecond\nthird, \\ and ;"},
+			ok:   true,
+		},
+		{
+			line: "EMAIL:a\\,b@example.com",
+			want: VCardProperty{Name: "EMAIL", Value: "a,b@example.com"},
+			ok:   true,
+		},
+		{
+			// Structured values keep escaped separators.
+			line: "ADR;TYPE=HOME:;;1 Main St\\, Unit 2;Town\\;ville;;;",
+			want: VCardProperty{Name: "ADR", Params: map[string][]string{"TYPE": {"HOME"}}, Value: ";;1 Main St, Unit 2;Town\\;ville;;;"},
+			ok:   true,
+		},
+		{
+			// Unknown escapes and a trailing backslash are left alone.
+			line: "NOTE:C:\\temp\\",
+			want: VCardProperty{Name: "NOTE", Value: "C:\\temp\\"},
+			ok:   true,
+		},
+		{
+			line: "PHOTO;ENCODING=b;TYPE=JPEG:/9j/4\\n",
+			want: VCardProperty{Name: "PHOTO", Params: map[string][]string{"ENCODING": {"b"}, "TYPE": {"JPEG"}}, Value: "/9j/4\\n"},
+			ok:   true,
+		},
+		{
+			line: "FN;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:J=C3=BCrgen",
+			want: VCardProperty{Name: "FN", Params: map[string][]string{"CHARSET": {"UTF-8"}, "ENCODING": {"QUOTED-PRINTABLE"}}, Value: "Jürgen"},
+			ok:   true,
+		},
+		{
+			// vCard 2.1 allows the encoding without a parameter name.
+			line: "NOTE;QUOTED-PRINTABLE:line one=0D=0Aline two",
+			want: VCardProperty{Name: "NOTE", Params: map[string][]string{"QUOTED-PRINTABLE": nil}, Value: "line one\r\nline two"},
+			ok:   true,
+		},
+		{
+			line: "NOTE;ENCODING=QUOTED-PRINTABLE:soft=\r\nbreak",
+			want: VCardProperty{Name: "NOTE", Params: map[string][]string{"ENCODING": {"QUOTED-PRINTABLE"}}, Value: "softbreak"},
+			ok:   true,
+		},
+		{
+			line: "no colon",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		got, ok := parseVCardProperty(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseVCardProperty(%q) ok = %t, want %t", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseVCardProperty(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseVCards(t *testing.T) {
+	data := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"FN;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:J=C3=BCrgen =\r\n" +
+		"M=C3=BCller\r\n" +
+		"TEL;CELL:+49 151 0000000\r\n" +
+		"TEL;HOME:+49 30 000000\r\n" +
+		"X-IRMC-CALL-DATETIME;MISSED:20180601T100000\r\n" +
+		"END:VCARD\r\n" +
+		"PROP:outside a vcard\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"FN:Smith\\, Alice\r\n" +
+		"EMAIL;TYPE=INTERNET:alice@exa\r\n" +
+		" mple.com\r\n" +
+		"X-IRMC-CALL-DATETIME;TYPE=RECEIVED:20180602T110000\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"FN:never ended\r\n"
+
+	cards := ParseVCards([]byte(data))
+	if len(cards) != 2 {
+		t.Fatalf("got %d cards, want 2", len(cards))
+	}
+	first := cards[0]
+	if first.FormattedName != "Jürgen Müller" {
+		t.Errorf("fn = %q", first.FormattedName)
+	}
+	if want := []string{"+49 151 0000000", "+49 30 000000"}; !reflect.DeepEqual(first.Telephones, want) {
+		t.Errorf("tel = %q, want %q", first.Telephones, want)
+	}
+	if first.CallType != "MISSED" || first.CallDateTime != "20180601T100000" {
+		t.Errorf("call = %q %q", first.CallType, first.CallDateTime)
+	}
+	if len(first.Properties) != 5 {
+		t.Errorf("got %d properties, want 5", len(first.Properties))
+	}
+
+	second := cards[1]
+	if second.FormattedName != "Smith, Alice" {
+		t.Errorf("fn = %q", second.FormattedName)
+	}
+	if want := []string{"alice@example.com"}; !reflect.DeepEqual(second.Emails, want) {
+		t.Errorf("email = %q, want %q", second.Emails, want)
+	}
+	if second.CallType != "RECEIVED" || second.CallDateTime != "20180602T110000" {
+		t.Errorf("call = %q %q", second.CallType, second.CallDateTime)
+	}
+}
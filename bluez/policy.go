@@ -0,0 +1,123 @@
+package bluez
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	adminPolicySetInterface    = "org.bluez.AdminPolicySet1"
+	adminPolicyStatusInterface = "org.bluez.AdminPolicyStatus1"
+)
+
+// ProfileGroups maps names accepted by ResolveServiceUUIDs to the service
+// UUIDs that make up the group.
+var ProfileGroups = map[string][]string{
+	"audio": {
+		"00001108-0000-1000-8000-00805f9b34fb", // headset
+		"0000110a-0000-1000-8000-00805f9b34fb", // a2dp source
+		"0000110b-0000-1000-8000-00805f9b34fb", // a2dp sink
+		"0000110c-0000-1000-8000-00805f9b34fb", // avrcp target
+		"0000110e-0000-1000-8000-00805f9b34fb", // avrcp
+		"0000110f-0000-1000-8000-00805f9b34fb", // avrcp controller
+		"00001112-0000-1000-8000-00805f9b34fb", // headset audio gateway
+		"0000111e-0000-1000-8000-00805f9b34fb", // handsfree
+		"0000111f-0000-1000-8000-00805f9b34fb", // handsfree audio gateway
+	},
+	"input": {
+		"00001124-0000-1000-8000-00805f9b34fb", // hid
+		"00001812-0000-1000-8000-00805f9b34fb", // hid over gatt
+		"0000180a-0000-1000-8000-00805f9b34fb", // device information
+		"0000180f-0000-1000-8000-00805f9b34fb", // battery
+		"00001813-0000-1000-8000-00805f9b34fb", // scan parameters
+	},
+	"file-transfer": {
+		"00001105-0000-1000-8000-00805f9b34fb", // obex object push
+		"00001106-0000-1000-8000-00805f9b34fb", // obex file transfer
+	},
+}
+
+// ResolveServiceUUIDs expands profile group names into their UUIDs, any
+// other name must be a full UUID or a 16 bit short UUID, ie: "110b".
+// Duplicates are removed and the result is sorted.
+func ResolveServiceUUIDs(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if group, ok := ProfileGroups[name]; ok {
+			for _, uuid := range group {
+				seen[uuid] = true
+			}
+			continue
+		}
+		uuid, err := normaliseUUID(name)
+		if err != nil {
+			return nil, err
+		}
+		seen[uuid] = true
+	}
+	uuids := []string{}
+	for uuid := range seen {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids, nil
+}
+
+func normaliseUUID(name string) (string, error) {
+	s := strings.TrimPrefix(strings.ToLower(name), "0x")
+	if len(s) == 4 {
+		s = "0000" + s + "-0000-1000-8000-00805f9b34fb"
+	}
+	if len(s) != 36 || strings.Count(s, "-") != 4 || strings.Trim(s, "0123456789abcdef-") != "" {
+		return "", fmt.Errorf("%q is not a profile group or uuid", name)
+	}
+	return s, nil
+}
+
+// ProfileGroupOf returns the name of the profile group a UUID belongs to,
+// or "" if it isn't in any group.
+func ProfileGroupOf(uuid string) string {
+	for name, group := range ProfileGroups {
+		for _, u := range group {
+			if strings.EqualFold(u, uuid) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// SetServiceAllowList restricts the adapter to the services in uuids, an
+// empty list allows every service.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/admin-policy-api.txt
+func (b *Bluez) SetServiceAllowList(adapterName string, uuids []string) error {
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	return ConvertError(b.conn.Object(dbusBluetoothPath, path).Call(adminPolicySetInterface+".SetServiceAllowList", 0, uuids).Store())
+}
+
+// ServiceAllowList returns the services the adapter is restricted to, an
+// empty list means every service is allowed.
+func (b *Bluez) ServiceAllowList(adapterName string) ([]string, error) {
+	path := dbus.ObjectPath("/org/bluez/" + adapterName)
+	v, err := b.conn.Object(dbusBluetoothPath, path).GetProperty(adminPolicyStatusInterface + ".ServiceAllowList")
+	if err != nil {
+		return nil, ConvertError(err)
+	}
+	uuids, _ := v.Value().([]string)
+	return uuids, nil
+}
+
+// IsAffectedByPolicy reports whether any of the services of a device are
+// blocked by the adapter's service allow list.
+func (b *Bluez) IsAffectedByPolicy(adapterName, deviceMac string) (bool, error) {
+	path := b.devicePath(adapterName, deviceMac)
+	v, err := b.conn.Object(dbusBluetoothPath, path).GetProperty(adminPolicyStatusInterface + ".IsAffectedByPolicy")
+	if err != nil {
+		return false, ConvertError(err)
+	}
+	affected, _ := v.Value().(bool)
+	return affected, nil
+}
@@ -0,0 +1,149 @@
+package bluez
+
+import (
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	profileManagerInterface = "org.bluez.ProfileManager1"
+	profileInterface        = "org.bluez.Profile1"
+
+	// SerialPortUUID is the service UUID of the serial port profile (SPP).
+	SerialPortUUID = "00001101-0000-1000-8000-00805f9b34fb"
+)
+
+// ProfileOptions are the options a profile is registered with.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/profile-api.txt
+type ProfileOptions struct {
+	Name string
+	// Role is either "client" or "server".
+	Role string
+	// Channel is the RFCOMM channel, 0 lets bluez choose one.
+	Channel               uint16
+	RequireAuthentication bool
+	RequireAuthorization  bool
+}
+
+func (o ProfileOptions) dbusOptions() map[string]dbus.Variant {
+	options := map[string]dbus.Variant{
+		"RequireAuthentication": dbus.MakeVariant(o.RequireAuthentication),
+		"RequireAuthorization":  dbus.MakeVariant(o.RequireAuthorization),
+	}
+	if o.Name != "" {
+		options["Name"] = dbus.MakeVariant(o.Name)
+	}
+	if o.Role != "" {
+		options["Role"] = dbus.MakeVariant(o.Role)
+	}
+	if o.Channel > 0 {
+		options["Channel"] = dbus.MakeVariant(o.Channel)
+	}
+	return options
+}
+
+// ProfileConnection is a connection bluez has handed to a profile, File is
+// the connected socket, ie: an RFCOMM socket.
+type ProfileConnection struct {
+	Device dbus.ObjectPath
+	File   *os.File
+}
+
+// Profile is an external profile registered with bluez, new connections
+// are delivered on Connections. Its exported methods are called by bluez
+// over dbus.
+type Profile struct {
+	b           *Bluez
+	path        dbus.ObjectPath
+	connections chan ProfileConnection
+
+	mu    sync.Mutex
+	files map[dbus.ObjectPath]*os.File
+}
+
+// RegisterProfile exports a profile at path and registers it with bluez for
+// the service uuid.
+func (b *Bluez) RegisterProfile(path dbus.ObjectPath, uuid string, options ProfileOptions) (*Profile, error) {
+	p := &Profile{
+		b:           b,
+		path:        path,
+		connections: make(chan ProfileConnection, 1),
+		files:       map[dbus.ObjectPath]*os.File{},
+	}
+	if err := b.conn.Export(p, path, profileInterface); err != nil {
+		return nil, err
+	}
+	if err := b.conn.Object(dbusBluetoothPath, "/org/bluez").Call(profileManagerInterface+".RegisterProfile", 0, path, uuid, options.dbusOptions()).Store(); err != nil {
+		b.conn.Export(nil, path, profileInterface)
+		return nil, ConvertError(err)
+	}
+	return p, nil
+}
+
+// Connections returns the channel new connections are delivered on.
+func (p *Profile) Connections() <-chan ProfileConnection {
+	return p.connections
+}
+
+// Unregister unregisters the profile from bluez and closes any open
+// connections.
+func (p *Profile) Unregister() error {
+	defer p.b.conn.Export(nil, p.path, profileInterface)
+	err := p.b.conn.Object(dbusBluetoothPath, "/org/bluez").Call(profileManagerInterface+".UnregisterProfile", 0, p.path).Store()
+	p.mu.Lock()
+	for device, f := range p.files {
+		f.Close()
+		delete(p.files, device)
+	}
+	p.mu.Unlock()
+	return ConvertError(err)
+}
+
+// Release is called by bluez when the profile is unregistered.
+func (p *Profile) Release() *dbus.Error {
+	return nil
+}
+
+// NewConnection is called by bluez when a device connects to the profile.
+func (p *Profile) NewConnection(device dbus.ObjectPath, fd dbus.UnixFD, properties map[string]dbus.Variant) *dbus.Error {
+	f := os.NewFile(uintptr(fd), string(device))
+	p.mu.Lock()
+	if old, ok := p.files[device]; ok {
+		old.Close()
+	}
+	p.files[device] = f
+	p.mu.Unlock()
+
+	select {
+	case p.connections <- ProfileConnection{Device: device, File: f}:
+		return nil
+	default:
+		// Nothing is waiting for a connection, so reject it rather than
+		// leaving the device connected to nothing.
+		p.closeConnection(device)
+		return dbus.NewError("org.bluez.Error.Rejected", []interface{}{"connection not accepted"})
+	}
+}
+
+// RequestDisconnection is called by bluez when a device is disconnected.
+func (p *Profile) RequestDisconnection(device dbus.ObjectPath) *dbus.Error {
+	p.closeConnection(device)
+	return nil
+}
+
+func (p *Profile) closeConnection(device dbus.ObjectPath) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if f, ok := p.files[device]; ok {
+		f.Close()
+		delete(p.files, device)
+	}
+}
+
+// ConnectProfile connects a single profile on a device, the connection is
+// delivered to the registered profile for the uuid.
+func (b *Bluez) ConnectProfile(adapterName, deviceMac, uuid string) error {
+	return ConvertError(b.CallDevice(adapterName, deviceMac, "ConnectProfile", 0, uuid).Store())
+}
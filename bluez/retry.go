@@ -0,0 +1,160 @@
+package bluez
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// RetryPolicy controls how failed operations are retried, only errors
+// accepted by Retryable are retried.
+type RetryPolicy struct {
+	// Attempts is the most times the operation is tried, values below 1
+	// try once.
+	Attempts int
+	// Delay is waited before the first retry and doubles for every later
+	// retry, up to MaxDelay. Up to half the delay again is added at random
+	// so several clients don't retry in lock step.
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// AttemptTimeout limits how long each attempt may take, 0 is no limit.
+	AttemptTimeout time.Duration
+	// OnRetry is called, when set, before waiting to retry.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryPolicy tries three times over a few seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:       3,
+	Delay:          time.Second,
+	MaxDelay:       30 * time.Second,
+	AttemptTimeout: 30 * time.Second,
+}
+
+// Backoff returns how long to wait before retry number retry, starting
+// at 1.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// Do calls op until it succeeds, fails with an error that isn't
+// retryable or runs out of attempts. The last error is returned.
+func (p RetryPolicy) Do(op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= p.Attempts || !Retryable(err) {
+			return err
+		}
+		delay := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// Retryable reports whether an operation that failed with err may succeed
+// when tried again, ie: a device that didn't respond in time.
+func Retryable(err error) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case *Error:
+			return e.Err == ErrPageTimeout || e.Err == ErrInProgress || e.Err == ErrConnectionAttemptFailed
+		case dbus.Error:
+			return isDbusTimeout(e.Name)
+		case *dbus.Error:
+			return isDbusTimeout(e.Name)
+		case interface{ Timeout() bool }:
+			if e.Timeout() {
+				return true
+			}
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+func isDbusTimeout(name string) bool {
+	return name == "org.freedesktop.DBus.Error.NoReply" || name == "org.freedesktop.DBus.Error.Timeout"
+}
+
+// ConnectWithRetry connects a device, retrying with policy. A device that
+// is already connected isn't an error, and when a connection is already
+// in progress, ie: started by a previous attempt that timed out, it is
+// waited for instead of failing.
+func (b *Bluez) ConnectWithRetry(adapterName, deviceMac string, policy RetryPolicy) error {
+	return policy.Do(func() error {
+		path := b.devicePath(adapterName, deviceMac)
+		call := b.conn.Object(dbusBluetoothPath, path).Go("org.bluez.Device1.Connect", 0, make(chan *dbus.Call, 1))
+		err := b.waitCall(call, policy.AttemptTimeout, fmt.Sprintf("device %s to connect", deviceMac))
+		switch {
+		case Is(err, ErrAlreadyConnected):
+			return nil
+		case Is(err, ErrInProgress):
+			return b.waitConnected(adapterName, deviceMac, policy.AttemptTimeout)
+		}
+		return err
+	})
+}
+
+// waitCall waits up to timeout for a call to finish, 0 waits for as long
+// as it takes. The call carries on in bluez after a timeout.
+func (b *Bluez) waitCall(call *dbus.Call, timeout time.Duration, waiting string) error {
+	if timeout <= 0 {
+		<-call.Done
+		return ConvertError(call.Err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		return ConvertError(call.Err)
+	case <-timer.C:
+		return &TimeoutError{Waiting: waiting}
+	}
+}
+
+// inProgressTimeout is how long to wait for an operation started elsewhere
+// when there is no timeout.
+const inProgressTimeout = 30 * time.Second
+
+// waitConnected waits for a connection that is in progress to complete.
+// ErrInProgress is returned if it hasn't completed in time, bluez doesn't
+// report failed connections so the caller can only try again.
+func (b *Bluez) waitConnected(adapterName, deviceMac string, timeout time.Duration) error {
+	return b.waitInProgress(adapterName, deviceMac, "Connected", "connection", timeout)
+}
+
+// waitInProgress waits for a boolean Device1 property to become true once
+// an operation in progress completes, ErrInProgress is returned if it
+// doesn't in time.
+func (b *Bluez) waitInProgress(adapterName, deviceMac, property, operation string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = inProgressTimeout
+	}
+	path := b.devicePath(adapterName, deviceMac)
+	err := b.WaitFor(fmt.Sprintf("device %s to be %s", deviceMac, strings.ToLower(property)), timeout, func(objects map[dbus.ObjectPath]ObjectState) bool {
+		return objects[path].Bool("org.bluez.Device1", property)
+	})
+	if _, ok := err.(*TimeoutError); ok {
+		return &Error{Name: "org.bluez.Error.InProgress", Message: operation + " still in progress after " + timeout.String(), Err: ErrInProgress}
+	}
+	return err
+}
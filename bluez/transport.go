@@ -0,0 +1,90 @@
+package bluez
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	mediaTransportInterface = "org.bluez.MediaTransport1"
+
+	// MaxVolume is the largest absolute volume a media transport accepts,
+	// as defined by AVRCP.
+	MaxVolume = 127
+)
+
+// MediaTransport holds the audio transport bluez creates for a connected
+// A2DP device.
+// https://git.kernel.org/pub/scm/bluetooth/bluez.git/tree/doc/media-api.txt
+type MediaTransport struct {
+	Path          string
+	Device        string
+	UUID          string
+	Codec         byte
+	Configuration []byte
+	// State is one of "idle", "pending" or "active".
+	State string
+	// Volume is only available when the device supports AVRCP absolute
+	// volume, HasVolume reports whether it was present.
+	Volume    uint16
+	HasVolume bool
+}
+
+// ConvertToMediaTransports converts a map of dbus objects to a common
+// MediaTransport structure.
+func (b *Bluez) ConvertToMediaTransports(path string, values map[string]map[string]dbus.Variant) []MediaTransport {
+	/*
+		org.bluez.MediaTransport1
+			Device => dbus.Variant{sig:dbus.Signature{str:"o"}, value:"/org/bluez/hci0/dev_2C_41_A1_49_37_CF"}
+			UUID => dbus.Variant{sig:dbus.Signature{str:"s"}, value:"0000110b-0000-1000-8000-00805f9b34fb"}
+			Codec => dbus.Variant{sig:dbus.Signature{str:"y"}, value:0x2}
+			Configuration => dbus.Variant{sig:dbus.Signature{str:"ay"}, value:[]uint8{0x80, 0x1, 0x4, 0x83, 0xe8, 0x0}}
+			State => dbus.Variant{sig:dbus.Signature{str:"s"}, value:"active"}
+			Volume => dbus.Variant{sig:dbus.Signature{str:"q"}, value:0x4f}
+	*/
+	transports := []MediaTransport{}
+	for k, v := range values {
+		switch k {
+		case mediaTransportInterface:
+			device, _ := v["Device"].Value().(dbus.ObjectPath)
+			uuid, _ := v["UUID"].Value().(string)
+			codec, _ := v["Codec"].Value().(byte)
+			configuration, _ := v["Configuration"].Value().([]byte)
+			state, _ := v["State"].Value().(string)
+			volume, hasVolume := v["Volume"].Value().(uint16)
+			transports = append(transports, MediaTransport{
+				Path:          path,
+				Device:        string(device),
+				UUID:          uuid,
+				Codec:         codec,
+				Configuration: configuration,
+				State:         state,
+				Volume:        volume,
+				HasVolume:     hasVolume,
+			})
+		}
+	}
+	return transports
+}
+
+// DeviceTransport returns the media transport for a connected device. A
+// transport only exists while an audio profile is connected.
+func (b *Bluez) DeviceTransport(adapterName, deviceMac string) (MediaTransport, error) {
+	path := string(b.devicePath(adapterName, deviceMac))
+	for _, t := range b.Transports {
+		if t.Device == path {
+			return t, nil
+		}
+	}
+	return MediaTransport{}, fmt.Errorf("no media transport found for device %q, is an audio profile connected?", deviceMac)
+}
+
+// SetTransportVolume sets the absolute volume for a media transport, the
+// volume must be between 0 and MaxVolume.
+func (b *Bluez) SetTransportVolume(transportPath string, volume uint16) error {
+	if volume > MaxVolume {
+		return fmt.Errorf("volume %d is larger than the maximum %d", volume, MaxVolume)
+	}
+	return ConvertError(b.conn.Object(dbusBluetoothPath, dbus.ObjectPath(transportPath)).Call("org.freedesktop.DBus.Properties.Set", 0, mediaTransportInterface, "Volume", dbus.MakeVariant(volume)).Store())
+}
@@ -0,0 +1,198 @@
+package bluez
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// ObjectState holds the properties of each interface of a bluez object,
+// ie: "org.bluez.Device1" and "org.bluez.Battery1" for a device.
+type ObjectState map[string]map[string]dbus.Variant
+
+// Has reports whether the object implements iface.
+func (s ObjectState) Has(iface string) bool {
+	_, ok := s[iface]
+	return ok
+}
+
+// Bool returns a boolean property, false if it isn't set.
+func (s ObjectState) Bool(iface, key string) bool {
+	v, _ := s[iface][key].Value().(bool)
+	return v
+}
+
+// String returns a string property, "" if it isn't set.
+func (s ObjectState) String(iface, key string) string {
+	v, _ := s[iface][key].Value().(string)
+	return v
+}
+
+// Byte returns a byte property, ie: the battery percentage, false is
+// returned if it isn't set.
+func (s ObjectState) Byte(iface, key string) (byte, bool) {
+	v, ok := s[iface][key].Value().(byte)
+	return v, ok
+}
+
+// ObjectWatcher keeps the state of every bluez object up to date, call
+// Update with each signal received on C.
+type ObjectWatcher struct {
+	// C receives every signal, not only those about bluez objects.
+	C       chan *dbus.Signal
+	Objects map[dbus.ObjectPath]ObjectState
+
+	b       *Bluez
+	matches []string
+}
+
+// objectWatcherMatches are the match rules for the signals an
+// ObjectWatcher needs.
+var objectWatcherMatches = []string{
+	"type='signal',interface='org.freedesktop.DBus.ObjectManager',path='/'",
+	"type='signal',sender='org.bluez',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'",
+	"type='signal',sender='org.bluez',interface='org.bluez.Device1',member='Disconnected'",
+}
+
+// WatchObjects starts watching for PropertiesChanged, InterfacesAdded,
+// InterfacesRemoved and device Disconnected signals and reads the current state of every object.
+// Objects that don't exist are missing from Objects. Stop must be called
+// once done.
+func (b *Bluez) WatchObjects() (*ObjectWatcher, error) {
+	// Watch before reading the current state so no change is missed. The
+	// channel gets every signal matching either rule.
+	w := &ObjectWatcher{C: make(chan *dbus.Signal, 16), b: b}
+	b.conn.Signal(w.C)
+	for _, match := range objectWatcherMatches {
+		if err := b.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store(); err != nil {
+			w.Stop()
+			return nil, fmt.Errorf("unable to add match %q: %v", match, err)
+		}
+		w.matches = append(w.matches, match)
+	}
+
+	if err := w.Reload(); err != nil {
+		w.Stop()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reload replaces Objects with the current state of every object. Signals
+// are delivered from separate goroutines so they can arrive out of order,
+// reloading corrects any state they left behind.
+func (w *ObjectWatcher) Reload() error {
+	managed, err := w.b.ManagedObjects()
+	if err != nil {
+		return err
+	}
+	w.Objects = map[dbus.ObjectPath]ObjectState{}
+	for path, ifaces := range managed {
+		w.Objects[path] = ifaces
+	}
+	return nil
+}
+
+// Update applies the changes in a signal to Objects, false is returned for
+// signals that don't change bluez objects.
+func (w *ObjectWatcher) Update(s *dbus.Signal) bool {
+	return applySignal(w.Objects, s)
+}
+
+// Stop stops watching for signals and removes the match rules.
+func (w *ObjectWatcher) Stop() {
+	for _, match := range w.matches {
+		w.b.conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match)
+	}
+	w.matches = nil
+	w.b.StopWatching(w.C)
+}
+
+// WaitFor calls satisfied with the state of every bluez object until it
+// returns true. The current state is checked first and then again after
+// each PropertiesChanged, InterfacesAdded or InterfacesRemoved signal.
+// Objects that don't exist are missing from objects. A timeout of 0 waits
+// forever, otherwise a TimeoutError describing waiting is returned.
+func (b *Bluez) WaitFor(waiting string, timeout time.Duration, satisfied func(objects map[dbus.ObjectPath]ObjectState) bool) error {
+	w, err := b.WatchObjects()
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+	if satisfied(w.Objects) {
+		return nil
+	}
+
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+	for {
+		select {
+		case s := <-w.C:
+			if w.Update(s) && satisfied(w.Objects) {
+				return nil
+			}
+		case <-timeoutChan:
+			return &TimeoutError{Waiting: waiting}
+		}
+	}
+}
+
+// applySignal updates objects with the changes in a signal, false is
+// returned for signals that don't change bluez objects.
+func applySignal(objects map[dbus.ObjectPath]ObjectState, s *dbus.Signal) bool {
+	if iface, changed, ok := PropertiesChanged(s); ok {
+		state, ok := objects[s.Path]
+		if !ok || !state.Has(iface) {
+			return false
+		}
+		for k, v := range changed {
+			state[iface][k] = v
+		}
+		if len(s.Body) > 2 {
+			invalidated, _ := s.Body[2].([]string)
+			for _, k := range invalidated {
+				delete(state[iface], k)
+			}
+		}
+		return true
+	}
+	if len(s.Body) != 2 {
+		return false
+	}
+	path, ok := s.Body[0].(dbus.ObjectPath)
+	if !ok {
+		return false
+	}
+	switch s.Name {
+	case "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
+		added, ok := s.Body[1].(map[string]map[string]dbus.Variant)
+		if !ok {
+			return false
+		}
+		if objects[path] == nil {
+			objects[path] = ObjectState{}
+		}
+		for iface, props := range added {
+			objects[path][iface] = props
+		}
+		return true
+	case "org.freedesktop.DBus.ObjectManager.InterfacesRemoved":
+		removed, ok := s.Body[1].([]string)
+		if !ok {
+			return false
+		}
+		for _, iface := range removed {
+			delete(objects[path], iface)
+		}
+		if len(objects[path]) == 0 {
+			delete(objects, path)
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+	"github.com/vishen/sluez/rfkill"
+)
+
+// adapterChangeTimeout is how long to wait for bluez to confirm an adapter
+// property has changed.
+const adapterChangeTimeout = 10 * time.Second
+
+// adapterCmd represents the adapter command
+var adapterCmd = &cobra.Command{
+	Use:   "adapter",
+	Short: "Control the power, visibility and name of an adapter",
+}
+
+var adapterPowerCmd = &cobra.Command{
+	Use:          "power on|off|toggle",
+	Short:        "Power an adapter on or off",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		props, err := b.GetAdapterProperties(adapter)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get properties of %q", adapter)
+		}
+		before, _ := props["Powered"].Value().(bool)
+		on := !before
+		if args[0] != "toggle" {
+			if on, err = parseOnOff(args[0]); err != nil {
+				return err
+			}
+		}
+		if on {
+			noUnblock, _ := cmd.Flags().GetBool("no-unblock")
+			if err := powerOnAdapter(b, adapter, !noUnblock); err != nil {
+				return err
+			}
+		} else {
+			debug("setting Powered=false on adapter=%s", adapter)
+			if err := b.SetAdapterPropertyAndWait(adapter, "Powered", false, adapterChangeTimeout); err != nil {
+				return errors.Wrapf(err, "unable to power off %q", adapter)
+			}
+		}
+		return p.print(adapterChange{Adapter: adapter, Property: "powered", Before: before, After: on}, func() {
+			fmt.Printf("%q powered: %t -> %t\n", adapter, before, on)
+		})
+	},
+}
+
+var adapterDiscoverableCmd = &cobra.Command{
+	Use:          "discoverable on|off",
+	Short:        "Make an adapter visible to other devices, or hide it",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAdapterMode(cmd, args[0], "Discoverable", "DiscoverableTimeout")
+	},
+}
+
+var adapterPairableCmd = &cobra.Command{
+	Use:          "pairable on|off",
+	Short:        "Allow or refuse new devices pairing with an adapter",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAdapterMode(cmd, args[0], "Pairable", "PairableTimeout")
+	},
+}
+
+var adapterNameCmd = &cobra.Command{
+	Use:          "name [NAME]",
+	Short:        "Print the name of an adapter, or set the name other devices see",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		a, ok := b.FindAdapter(adapter)
+		if !ok {
+			return withExitCode(exitNotFound, errors.Errorf("no adapter found named %q", adapter))
+		}
+		if len(args) == 0 {
+			return p.print(adapterName{Adapter: adapter, Alias: a.Alias, Name: a.Name}, func() {
+				fmt.Printf("alias=%q name=%q\n", a.Alias, a.Name)
+			})
+		}
+		// The adapter name is the system hostname, the alias is what is
+		// shown to other devices.
+		debug("setting Alias=%q on adapter=%s", args[0], adapter)
+		if err := b.SetAdapterPropertyAndWait(adapter, "Alias", args[0], adapterChangeTimeout); err != nil {
+			return errors.Wrapf(err, "unable to set name of %q", adapter)
+		}
+		return p.print(adapterChange{Adapter: adapter, Property: "alias", Before: a.Alias, After: args[0]}, func() {
+			fmt.Printf("%q alias: %q -> %q\n", adapter, a.Alias, args[0])
+		})
+	},
+}
+
+// adapterChange is a property of an adapter changed by a command.
+type adapterChange struct {
+	Adapter  string      `json:"adapter"`
+	Property string      `json:"property"`
+	Before   interface{} `json:"before"`
+	After    interface{} `json:"after"`
+}
+
+// adapterName is printed by 'adapter name' without a name.
+type adapterName struct {
+	Adapter string `json:"adapter"`
+	Alias   string `json:"alias"`
+	Name    string `json:"name"`
+}
+
+// powerOnAdapter powers on an adapter, first removing an rfkill soft
+// block when unblock is set. bluez only reports a blocked adapter as
+// failing to power on, so rfkill is checked to explain why.
+func powerOnAdapter(b *bluez.Bluez, adapter string, unblock bool) error {
+	device, found, err := rfkill.Find(adapter)
+	if err != nil {
+		debug("unable to read rfkill state: %v", err)
+	}
+	if found && device.Hard {
+		return withExitCode(exitNotReady, errors.Errorf("unable to power on %q: it is hard blocked by rfkill, which can only be undone with the hardware wireless switch or a bios setting", adapter))
+	}
+	if found && device.Soft {
+		if !unblock {
+			return withExitCode(exitNotReady, errors.Errorf("unable to power on %q: it is soft blocked by rfkill, run 'rfkill unblock %d'", adapter, device.Index))
+		}
+		debug("removing rfkill soft block index=%d on adapter=%s", device.Index, adapter)
+		if err := rfkill.Unblock(device.Index); err != nil {
+			if os.IsPermission(err) {
+				return withExitCode(exitPermission, errors.Errorf("unable to power on %q: it is soft blocked by rfkill and there is no permission to write %s, run 'rfkill unblock %d' as root", adapter, rfkill.DevicePath, device.Index))
+			}
+			return errors.Wrapf(err, "unable to remove rfkill soft block on %q", adapter)
+		}
+		fmt.Fprintf(os.Stderr, "removed rfkill soft block on %q\n", adapter)
+	}
+
+	debug("setting Powered=true on adapter=%s", adapter)
+	deadline := time.Now().Add(adapterChangeTimeout)
+	for {
+		err := b.SetAdapterPropertyAndWait(adapter, "Powered", true, adapterChangeTimeout)
+		if err == nil {
+			return nil
+		}
+		// The adapter takes a moment to become usable after being unblocked.
+		if !found || !device.Soft || time.Now().After(deadline) {
+			return errors.Wrapf(err, "unable to power on %q", adapter)
+		}
+		debug("retrying power on adapter=%s: %v", adapter, err)
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// setAdapterMode turns a boolean adapter property on or off. A timeout is
+// passed on to bluez when turning the property on, and with --revert
+// sluez waits for the timeout and restores the previous state itself.
+func setAdapterMode(cmd *cobra.Command, state, property, timeoutProperty string) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	on, err := parseOnOff(state)
+	if err != nil {
+		return err
+	}
+	adapter, _ := cmd.Flags().GetString("adapter")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	revert, _ := cmd.Flags().GetBool("revert")
+	if revert && timeout <= 0 {
+		return usageError("--revert requires a --timeout")
+	}
+	// bluez takes whole seconds and 0 is never, so a shorter timeout
+	// would never turn it off.
+	if timeout < 0 || timeout > 0 && timeout < time.Second {
+		return usageError("--timeout %s is invalid, expected 0 or at least 1s", timeout)
+	}
+	b, err := newBluez(cmd)
+	if err != nil {
+		return errors.Wrap(err, "unable to get bluez client")
+	}
+	props, err := b.GetAdapterProperties(adapter)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get properties of %q", adapter)
+	}
+	before, _ := props[property].Value().(bool)
+
+	name := strings.ToLower(property)
+	if on && cmd.Flags().Changed("timeout") {
+		seconds := uint32((timeout + time.Second - 1) / time.Second)
+		debug("setting %s=%d on adapter=%s", timeoutProperty, seconds, adapter)
+		if err := b.SetAdapterProperty(adapter, timeoutProperty, seconds); err != nil {
+			return errors.Wrapf(err, "unable to set %s timeout on %q", name, adapter)
+		}
+	}
+	debug("setting %s=%t on adapter=%s", property, on, adapter)
+	if err := b.SetAdapterPropertyAndWait(adapter, property, on, adapterChangeTimeout); err != nil {
+		return errors.Wrapf(err, "unable to turn %s %s on %q", name, onOff(on), adapter)
+	}
+	err = p.print(adapterChange{Adapter: adapter, Property: name, Before: before, After: on}, func() {
+		fmt.Printf("%q %s: %t -> %t\n", adapter, name, before, on)
+	})
+	if err != nil || !revert || before == on {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "reverting %s in %s, press ctrl-c to revert now\n", name, timeout)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-stop:
+	case <-time.After(timeout):
+	}
+	// bluez may have already reverted the state when its own timeout is the
+	// same, in which case this is a no-op.
+	if err := b.SetAdapterPropertyAndWait(adapter, property, before, adapterChangeTimeout); err != nil {
+		return errors.Wrapf(err, "unable to revert %s on %q", name, adapter)
+	}
+	return p.print(adapterChange{Adapter: adapter, Property: name, Before: on, After: before}, func() {
+		fmt.Printf("%q %s: %t -> %t\n", adapter, name, on, before)
+	})
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, usageError("%q is invalid, expected on or off", s)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func init() {
+	for _, c := range []*cobra.Command{adapterDiscoverableCmd, adapterPairableCmd} {
+		c.Flags().Duration("timeout", 0, "How long until bluez turns it off again, 0 is never. Defaults to the bluez configured timeout")
+		c.Flags().Bool("revert", false, "Wait for --timeout and then restore the previous state, even if bluez has no timeout")
+	}
+	adapterPowerCmd.Flags().Bool("no-unblock", false, "Don't remove an rfkill soft block when powering on")
+	adapterCmd.AddCommand(adapterPowerCmd)
+	adapterCmd.AddCommand(adapterDiscoverableCmd)
+	adapterCmd.AddCommand(adapterPairableCmd)
+	adapterCmd.AddCommand(adapterNameCmd)
+	rootCmd.AddCommand(adapterCmd)
+}
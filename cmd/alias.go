@@ -0,0 +1,123 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Set or reset the alias of a device or adapter",
+	Long: `Set or reset the alias of a device or adapter.
+
+Aliases are matched before names by --device-name, so identical devices can
+be told apart by giving each an alias.`,
+}
+
+var aliasSetCmd = &cobra.Command{
+	Use:          "set ALIAS [DEVICE]",
+	Short:        "Set the alias of a device, or of the adapter with --target=adapter",
+	Args:         cobra.RangeArgs(1, 2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAlias(cmd, args[0], deviceArgs(args, 1))
+	},
+}
+
+var aliasResetCmd = &cobra.Command{
+	Use:          "reset [DEVICE]",
+	Short:        "Reset the alias of a device, or of the adapter with --target=adapter, back to its name",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAlias(cmd, "", args)
+	},
+}
+
+// aliasResult is the alias of a device or adapter before and after it
+// was changed.
+type aliasResult struct {
+	Target string `json:"target"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func setAlias(cmd *cobra.Command, alias string, args []string) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	target, _ := cmd.Flags().GetString("target")
+	b, err := newBluez(cmd)
+	if err != nil {
+		return errors.Wrap(err, "unable to get bluez client")
+	}
+
+	switch target {
+	case "adapter":
+		if len(args) > 0 {
+			return usageError("a device can't be given with --target=adapter")
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		a, ok := b.FindAdapter(adapter)
+		if !ok {
+			return withExitCode(exitNotFound, errors.Errorf("no adapter found named %q", adapter))
+		}
+		debug("setting alias=%q on adapter=%s", alias, adapter)
+		if err := b.SetAdapterAlias(adapter, alias); err != nil {
+			return errors.Wrapf(err, "unable to set alias on %q", adapter)
+		}
+		if err := b.PopulateCache(); err != nil {
+			return errors.Wrap(err, "unable to refresh adapters")
+		}
+		after, _ := b.FindAdapter(adapter)
+		return printAlias(p, adapter, a.Alias, after.Alias)
+	case "device":
+		device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+		d, _ := b.FindDevice(device)
+		debug("setting alias=%q on adapter=%s device=%s", alias, adapter, device)
+		if err := b.SetDeviceAlias(adapter, device, alias); err != nil {
+			return errors.Wrapf(err, "unable to set alias on %q", device)
+		}
+		props, err := b.GetDeviceProperties(adapter, device)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get alias of %q", device)
+		}
+		after, _ := props["Alias"].Value().(string)
+		return printAlias(p, device, d.Alias, after)
+	}
+	return usageError("%q is an invalid target, expected device or adapter", target)
+}
+
+func printAlias(p *printer, target, before, after string) error {
+	return p.print(aliasResult{Target: target, Before: before, After: after}, func() {
+		fmt.Printf("%q alias: %q -> %q\n", target, before, after)
+	})
+}
+
+func init() {
+	aliasCmd.PersistentFlags().String("target", "device", "What to alias: device or adapter")
+	aliasCmd.AddCommand(aliasSetCmd)
+	aliasCmd.AddCommand(aliasResetCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
@@ -0,0 +1,187 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+	"github.com/vishen/sluez/pulseaudio"
+)
+
+// audioCardTimeout is how long to wait for the sound server to create the
+// card for a device that has just connected.
+const audioCardTimeout = 5 * time.Second
+
+// audioProfileCmd represents the audio-profile command
+var audioProfileCmd = &cobra.Command{
+	Use:   "audio-profile [PROFILE [DEVICE]]",
+	Short: "Show or set the pulseaudio card profile of a connected audio device, ie: 'a2dp', 'headset' or 'off'",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		device, _, err := deviceArgAndAdapter(b, cmd, deviceArgs(args, 1))
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+
+		if len(args) > 0 {
+			profile, err := setAudioProfile(device, args[0])
+			if err != nil {
+				return errors.Wrapf(err, "unable to set audio profile for %q", device)
+			}
+			return p.print(audioProfileResult{Device: device, Active: profile}, func() {
+				fmt.Printf("successfully set audio profile %q for %q\n", profile, device)
+			})
+		}
+
+		pa, err := pulseaudio.Dial(pulseaudio.ServerPath())
+		if err != nil {
+			return errors.Wrap(err, "unable to connect to sound server")
+		}
+		defer pa.Close()
+		card, err := pa.BluetoothCard(device)
+		if err != nil {
+			return withExitCode(exitNotFound, errors.Wrap(err, "unable to get audio card"))
+		}
+		r := audioProfileResult{Device: device, Card: card.Name, Active: card.ActiveProfile, Profiles: []audioProfile{}}
+		for _, profile := range card.Profiles {
+			r.Profiles = append(r.Profiles, audioProfile{
+				Name:        profile.Name,
+				Description: profile.Description,
+				Available:   profile.Available,
+				Active:      profile.Name == card.ActiveProfile,
+			})
+		}
+		return p.print(r, func() {
+			fmt.Printf("Profiles for %s:\n", r.Card)
+			for i, profile := range r.Profiles {
+				fmt.Printf("%d) name=%q description=%q available=%t active=%t\n", i+1, profile.Name, profile.Description, profile.Available, profile.Active)
+			}
+		})
+	},
+}
+
+// audioProfileResult is the active audio profile of a device, and every
+// profile of its card when they are listed.
+type audioProfileResult struct {
+	Device   string         `json:"device"`
+	Card     string         `json:"card,omitempty"`
+	Active   string         `json:"active"`
+	Profiles []audioProfile `json:"profiles,omitempty"`
+}
+
+type audioProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Available   bool   `json:"available"`
+	Active      bool   `json:"active"`
+}
+
+// setAudioProfile sets the card profile for a bluetooth device, waiting for
+// the sound server to create the card if the device has only just been
+// connected. The name of the profile that was set is returned.
+func setAudioProfile(device, profile string) (string, error) {
+	pa, err := pulseaudio.Dial(pulseaudio.ServerPath())
+	if err != nil {
+		return "", err
+	}
+	defer pa.Close()
+
+	var card pulseaudio.Card
+	deadline := time.Now().Add(audioCardTimeout)
+	for {
+		card, err = pa.BluetoothCard(device)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		debug("waiting for audio card for %q: %v", device, err)
+		time.Sleep(250 * time.Millisecond)
+	}
+	if err != nil {
+		return "", err
+	}
+	p, err := card.FindProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	if card.ActiveProfile == p.Name {
+		debug("audio profile %q is already active on %q", p.Name, card.Name)
+		return p.Name, nil
+	}
+	debug("setting audio profile on card=%s from %q to %q", card.Name, card.ActiveProfile, p.Name)
+	if err := pa.SetCardProfile(card.Name, p.Name); err != nil {
+		return "", err
+	}
+	return p.Name, nil
+}
+
+// addAudioFlags adds the flags read by connectAudio and setupAudio.
+func addAudioFlags(c *cobra.Command) {
+	c.Flags().String("audio-profile", "a2dp", "Audio profile to select once an audio device is connected, ie: 'a2dp' or 'headset'. An empty value leaves the profile unchanged")
+	c.Flags().Bool("no-audio-switch", false, "Don't make a connected audio device the default output or move existing audio streams to it")
+}
+
+// connectAudio sets the audio profile requested with --audio-profile after
+// connecting a device and then moves audio output to it, unless
+// --no-audio-switch is set. Devices that can't play audio are skipped.
+func connectAudio(b *bluez.Bluez, cmd *cobra.Command, device string) {
+	if d, ok := b.FindDevice(device); !ok || !d.HasUUID(bluez.AudioSinkUUID) {
+		debug("device %q is not an audio sink, skipping audio setup", device)
+		return
+	}
+	setupAudio(cmd, device)
+}
+
+// setupAudio sets the --audio-profile of an audio device and moves audio
+// output to it unless --no-audio-switch is set, settings configured for the
+// device are used when the flags aren't given. Failures are reported on
+// stderr.
+func setupAudio(cmd *cobra.Command, device string) {
+	// NOTE: Need to manually set the card profile for pulseaudio, this _should_
+	// happen already, but for some reason it doesn't always happen. This tends
+	// to happen when the computer has been idle for a while.
+	if profile := audioProfileFor(cmd, device); profile != "" {
+		p, err := setAudioProfile(device, profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to set audio profile for %q: %v\n", device, err)
+		} else {
+			debug("audio profile %q set for %q", p, device)
+		}
+	}
+
+	if !audioSwitchFor(cmd, device) {
+		return
+	}
+	if err := switchAudio(device); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to switch audio output to %q: %v\n", device, err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(audioProfileCmd)
+}
@@ -0,0 +1,285 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/pulseaudio"
+)
+
+// previousAudio is the default sink and source before a bluetooth device
+// was switched to, so they can be restored on disconnect.
+type previousAudio struct {
+	Sink   string `json:"sink"`
+	Source string `json:"source"`
+}
+
+// previousAudioPath returns where the previous audio of a device is kept,
+// creating the directory if needed. Outside of $XDG_RUNTIME_DIR the
+// directory is in a shared temp dir, so it is per user and has to be
+// owned by us and not readable by anyone else.
+func previousAudioPath(device string) (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir != "" {
+		dir = filepath.Join(dir, "sluez")
+	} else {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("sluez-%d", os.Getuid()))
+	}
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", errors.Errorf("%q isn't a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return "", errors.Errorf("%q is owned by another user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return "", errors.Errorf("%q is accessible by other users", dir)
+	}
+	return filepath.Join(dir, "audio-"+strings.Replace(device, ":", "_", -1)+".json"), nil
+}
+
+// switchAudio makes the sink, and source if the profile has one, of a
+// bluetooth device the default and moves all existing streams to them.
+func switchAudio(device string) error {
+	pa, err := pulseaudio.Dial(pulseaudio.ServerPath())
+	if err != nil {
+		return err
+	}
+	defer pa.Close()
+
+	// The sink is created after the card, and recreated whenever the
+	// profile changes, so wait for it to show up.
+	var (
+		sink  pulseaudio.Sink
+		found bool
+		card  pulseaudio.Card
+	)
+	deadline := time.Now().Add(audioCardTimeout)
+	for {
+		card, err = pa.BluetoothCard(device)
+		if err == nil {
+			sink, found, err = pa.CardSink(card.Index)
+		}
+		if found || time.Now().After(deadline) {
+			break
+		}
+		debug("waiting for audio sink for %q", device)
+		time.Sleep(250 * time.Millisecond)
+	}
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.Errorf("no audio sink found for card %q", card.Name)
+	}
+	source, hasSource, err := pa.CardSource(card.Index)
+	if err != nil {
+		return err
+	}
+
+	info, err := pa.ServerInfo()
+	if err != nil {
+		return err
+	}
+	// Don't overwrite the saved defaults when the device is already the
+	// default, ie: when connecting an already connected device.
+	if info.DefaultSink != sink.Name {
+		if err := savePreviousAudio(device, previousAudio{Sink: info.DefaultSink, Source: info.DefaultSource}); err != nil {
+			debug("unable to save previous audio defaults: %v", err)
+		}
+	}
+
+	debug("setting default sink to %q", sink.Name)
+	if err := pa.SetDefaultSink(sink.Name); err != nil {
+		return errors.Wrap(err, "unable to set default sink")
+	}
+	inputs, err := pa.SinkInputs()
+	if err != nil {
+		return err
+	}
+	for _, s := range inputs {
+		if s.Device == sink.Index {
+			continue
+		}
+		debug("moving playback stream %q to %q", s.Name, sink.Name)
+		if err := pa.MoveSinkInput(s.Index, sink.Index); err != nil {
+			debug("unable to move playback stream %q: %v", s.Name, err)
+		}
+	}
+
+	if !hasSource {
+		return nil
+	}
+	debug("setting default source to %q", source.Name)
+	if err := pa.SetDefaultSource(source.Name); err != nil {
+		return errors.Wrap(err, "unable to set default source")
+	}
+	outputs, err := pa.SourceOutputs()
+	if err != nil {
+		return err
+	}
+	for _, s := range outputs {
+		if s.Device == source.Index {
+			continue
+		}
+		debug("moving recording stream %q to %q", s.Name, source.Name)
+		if err := pa.MoveSourceOutput(s.Index, source.Index); err != nil {
+			debug("unable to move recording stream %q: %v", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// restoreAudio restores the default sink and source that were in use
+// before switchAudio was called for a device, and moves streams back to
+// them. Nothing is done if the audio was never switched.
+func restoreAudio(device string) error {
+	path, err := previousAudioPath(device)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	previous := previousAudio{}
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return errors.Wrapf(err, "unable to read %q", path)
+	}
+
+	pa, err := pulseaudio.Dial(pulseaudio.ServerPath())
+	if err != nil {
+		return err
+	}
+	defer pa.Close()
+
+	// Only streams playing through the device are moved back, streams the
+	// user has put on other outputs since are left alone.
+	card, err := pa.BluetoothCard(device)
+	if err != nil {
+		debug("not moving streams, no audio card for %q: %v", device, err)
+	}
+
+	sinks, err := pa.Sinks()
+	if err != nil {
+		return err
+	}
+	deviceSinks := map[uint32]bool{}
+	for _, sink := range sinks {
+		if card.Name != "" && sink.Card == card.Index {
+			deviceSinks[sink.Index] = true
+		}
+	}
+	for _, sink := range sinks {
+		if sink.Name != previous.Sink {
+			continue
+		}
+		debug("restoring default sink to %q", sink.Name)
+		if err := pa.SetDefaultSink(sink.Name); err != nil {
+			return errors.Wrap(err, "unable to set default sink")
+		}
+		inputs, err := pa.SinkInputs()
+		if err != nil {
+			return err
+		}
+		for _, s := range inputs {
+			if !deviceSinks[s.Device] {
+				continue
+			}
+			debug("moving playback stream %q to %q", s.Name, sink.Name)
+			if err := pa.MoveSinkInput(s.Index, sink.Index); err != nil {
+				debug("unable to move playback stream %q: %v", s.Name, err)
+			}
+		}
+	}
+
+	sources, err := pa.Sources()
+	if err != nil {
+		return err
+	}
+	deviceSources := map[uint32]bool{}
+	for _, source := range sources {
+		if card.Name != "" && source.Card == card.Index {
+			deviceSources[source.Index] = true
+		}
+	}
+	for _, source := range sources {
+		if source.Name != previous.Source {
+			continue
+		}
+		debug("restoring default source to %q", source.Name)
+		if err := pa.SetDefaultSource(source.Name); err != nil {
+			return errors.Wrap(err, "unable to set default source")
+		}
+		outputs, err := pa.SourceOutputs()
+		if err != nil {
+			return err
+		}
+		for _, s := range outputs {
+			if !deviceSources[s.Device] {
+				continue
+			}
+			debug("moving recording stream %q to %q", s.Name, source.Name)
+			if err := pa.MoveSourceOutput(s.Index, source.Index); err != nil {
+				debug("unable to move recording stream %q: %v", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func savePreviousAudio(device string, previous previousAudio) error {
+	path, err := previousAudioPath(device)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(previous)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// disconnectAudio moves audio back to the previous default before a
+// device is disconnected, unless --no-audio-switch is set or audio-switch
+// is configured off for the device.
+func disconnectAudio(cmd *cobra.Command, device string) {
+	if !audioSwitchFor(cmd, device) {
+		return
+	}
+	if err := restoreAudio(device); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to restore previous audio output: %v\n", err)
+	}
+}
@@ -16,61 +16,409 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/godbus/dbus"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+	"github.com/vishen/sluez/inventory"
+)
+
+// Outcomes of an auto step.
+const (
+	stepDone    = "done"
+	stepSkipped = "skipped"
+	stepFailed  = "failed"
+	// stepWarning is a step that failed without stopping auto, ie: the
+	// sound server not running.
+	stepWarning = "warning"
 )
 
+// stepResult is the outcome of one step of auto.
+type stepResult struct {
+	Step   string `json:"step"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// autoResult is printed once auto finishes, Steps has every step that was
+// run in order.
+type autoResult struct {
+	Device  string       `json:"device"`
+	Adapter string       `json:"adapter"`
+	Success bool         `json:"success"`
+	Steps   []stepResult `json:"steps"`
+}
+
 // autoCmd represents the auto command
 var autoCmd = &cobra.Command{
-	Use:   "auto",
+	Use:   "auto [DEVICE]",
 	Short: "Try and automatically connect the device to the adapter.",
+	Long: `Auto does whatever is needed to get a device connected, in order it will:
+
+  power     power on the adapter, removing any rfkill soft block
+  unblock   unblock the device if it is blocked
+  discover  discover the device if bluez doesn't know about it yet
+  pair      pair the device
+  trust     trust the device so it can reconnect by itself
+  connect   connect the device
+  profile   select the --audio-profile of audio devices
+  audio     make an audio device the default output
+
+Each step checks the current state first and is skipped if there is
+nothing to do, so running auto again after it was interrupted picks up
+where it left off. Pairing or connecting that is still in progress is
+waited for.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		b, err := newBluez(cmd)
+		p, err := newPrinter(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return err
 		}
-		device, adapter, err := deviceAndAdapter(b, cmd)
+		policy, err := retryPolicy(cmd)
 		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		r := &autoRun{b: b, cmd: cmd, p: p, policy: policy}
+		if err := r.selectDevice(args); err != nil {
 			return errors.Wrap(err, "unable to determine device and/or adapter")
 		}
-		if err := b.SetAdapterProperty(adapter, "Powered", true); err != nil {
-			fmt.Printf("unable to power on adapter: %v\n", err)
-			return nil
+
+		steps := []struct {
+			name string
+			run  func() (string, string, error)
+		}{
+			{"power", r.power},
+			{"unblock", r.unblock},
+			{"discover", r.discover},
+			{"pair", r.pair},
+			{"trust", r.trust},
+			{"connect", r.connect},
+			{"profile", r.profile},
+			{"audio", r.audio},
+		}
+		for _, s := range steps {
+			status, detail, err := s.run()
+			if err := r.report(s.name, status, detail, err); err != nil {
+				return err
+			}
 		}
+		r.result.Success = true
+		return p.print(r.result, func() {
+			fmt.Printf("successfully connected %q and %q\n", r.result.Device, r.result.Adapter)
+		})
+	},
+}
 
-		// TODO(vishen); Check to see if the device needs to be paired.
+// autoRun holds the state shared by the steps of auto. Each step returns
+// its status and a detail for the user.
+type autoRun struct {
+	b      *bluez.Bluez
+	cmd    *cobra.Command
+	p      *printer
+	policy bluez.RetryPolicy
 
-		// TODO(vishen): If we are already connecting, but the process closed during the
-		// process, if we restart should we watch for connect signals?
-		// [sluez] connecting to adapter=hci0 device=2C:41:A1:49:37:CF
-		// unable to connect to device "2C:41:A1:49:37:CF": Operation already in progress
+	// deviceName is set instead of the device address when the device
+	// still has to be discovered.
+	deviceName string
+	result     autoResult
+}
 
-		// TODO(vishen): This is an exact copy of the 'connect' command
-		// code, is it possible to combine the two?
-		debug("connecting to adapter=%s device=%s", adapter, device)
-		for i := 0; i < 2; i++ {
-			if err := b.Connect(adapter, device); err != nil {
-				fmt.Printf("unable to connect to device %q: %v\n", device, err)
-				return nil
-			}
+// report records the outcome of a step, printing it straight away for the
+// text format. A failed step ends auto with err.
+func (r *autoRun) report(step, status, detail string, err error) error {
+	s := stepResult{Step: step, Status: status, Detail: detail}
+	if err != nil {
+		s.Error = err.Error()
+		if status != stepWarning {
+			s.Status = stepFailed
 		}
-		fmt.Printf("successfully connected %q and %q\n", device, adapter)
-		// NOTE: Need to manually set the card profile for pulseaudio, this _should_
-		// happen already, but for some reason it doesn't always happen. This tends
-		// to happen when the computer has been idle for a while.
-		// pactl set-card-profile bluez_card.2C_41_A1_49_37_CF a2dp_sink
-		// TODO(vishen): pulseaudio can be controlled via dbus, look at using that
-		// instead
-		exec.Command("pactl", "set-card-profile", fmt.Sprintf("bluez_card.%s", strings.Replace(device, ":", "_", -1)), "a2dp_sink").Run()
+	}
+	r.result.Steps = append(r.result.Steps, s)
+	if !r.p.structured() {
+		switch {
+		case s.Error != "":
+			fmt.Printf("%s: %s: %s\n", step, s.Status, s.Error)
+		case s.Detail != "":
+			fmt.Printf("%s: %s, %s\n", step, s.Status, s.Detail)
+		default:
+			fmt.Printf("%s: %s\n", step, s.Status)
+		}
+	}
+	if s.Status != stepFailed {
+		return nil
+	}
+	err = errors.Wrapf(err, "unable to %s %q", step, r.target())
+	if r.p.structured() {
+		if printErr := r.p.print(r.result, nil); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// target is the device, or the name being discovered.
+func (r *autoRun) target() string {
+	if r.result.Device == "" {
+		return r.deviceName
+	}
+	return r.result.Device
+}
 
+// selectDevice picks the device like the other commands do, except that a
+// name matching no known device, given as an arg or with --device-name, is
+// discovered.
+func (r *autoRun) selectDevice(args []string) error {
+	r.result.Adapter, _ = r.cmd.Flags().GetString("adapter")
+	if r.result.Adapter == "" {
+		return usageError("--adapter is required")
+	}
+	r.result.Steps = []stepResult{}
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else if device, _ := r.cmd.Flags().GetString("device"); device == "" {
+		name, _ = r.cmd.Flags().GetString("device-name")
+	}
+	_, nickname := inv.Find(name)
+	_, _, objectPath := objectPathAddress(name)
+	if name != "" && !nickname && !isMacAddress(name) && !objectPath && !strings.HasPrefix(name, inventory.TagPrefix) && len(matchDeviceName(r.b, name)) == 0 {
+		r.deviceName = name
 		return nil
-	},
+	}
+	// The adapter can come from an object path given as the device.
+	device, adapter, err := deviceArgAndAdapter(r.b, r.cmd, args)
+	if err != nil {
+		return err
+	}
+	r.result.Device, r.result.Adapter = device, adapter
+	return nil
+}
+
+// deviceProperties returns the properties of the device, nil if bluez
+// doesn't know about it.
+func (r *autoRun) deviceProperties() (map[string]dbus.Variant, error) {
+	if r.result.Device == "" {
+		return nil, nil
+	}
+	props, err := r.b.GetDeviceProperties(r.result.Adapter, r.result.Device)
+	if bluez.Is(err, bluez.ErrDoesNotExist) {
+		return nil, nil
+	}
+	return props, err
+}
+
+// deviceBool returns a boolean property of the device.
+func (r *autoRun) deviceBool(property string) (bool, error) {
+	props, err := r.deviceProperties()
+	if err != nil {
+		return false, err
+	}
+	v, _ := props[property].Value().(bool)
+	return v, nil
+}
+
+func (r *autoRun) power() (string, string, error) {
+	props, err := r.b.GetAdapterProperties(r.result.Adapter)
+	if err != nil {
+		return stepFailed, "", err
+	}
+	if powered, _ := props["Powered"].Value().(bool); powered {
+		return stepSkipped, "already powered", nil
+	}
+	if err := powerOnAdapter(r.b, r.result.Adapter, true); err != nil {
+		return stepFailed, "", err
+	}
+	return stepDone, "", nil
+}
+
+func (r *autoRun) unblock() (string, string, error) {
+	props, err := r.deviceProperties()
+	switch {
+	case err != nil:
+		return stepFailed, "", err
+	case props == nil:
+		return stepSkipped, "not known yet", nil
+	}
+	if blocked, _ := props["Blocked"].Value().(bool); !blocked {
+		return stepSkipped, "not blocked", nil
+	}
+	if err := r.b.SetDeviceProperty(r.result.Adapter, r.result.Device, "Blocked", false); err != nil {
+		return stepFailed, "", err
+	}
+	return stepDone, "", nil
+}
+
+func (r *autoRun) discover() (string, string, error) {
+	props, err := r.deviceProperties()
+	switch {
+	case err != nil:
+		return stepFailed, "", err
+	case props != nil:
+		return stepSkipped, "already known", nil
+	}
+	timeout, _ := r.cmd.Flags().GetDuration("discover-timeout")
+	adapter := r.result.Adapter
+	// Discovery left running by an interrupted run reports in progress.
+	if err := r.b.StartDiscovery(adapter); err != nil && !bluez.Is(err, bluez.ErrInProgress) {
+		return stepFailed, "", errors.Wrap(err, "unable to start discovery")
+	}
+	defer r.b.StopDiscovery(adapter)
+	r.p.info("waiting for %q to be discovered, make sure to put device into pairing mode\n", r.target())
+
+	// A name is matched like findDevice does, exact matches are preferred
+	// and more than one match is ambiguous rather than pairing whichever
+	// device was seen first.
+	adapterPath := dbus.ObjectPath("/org/bluez/" + adapter)
+	var exact, fuzzy []string
+	err = r.b.WaitFor(fmt.Sprintf("%q to be discovered", r.target()), timeout, func(objects map[dbus.ObjectPath]bluez.ObjectState) bool {
+		exact, fuzzy = nil, nil
+		for _, state := range objects {
+			if !state.Has("org.bluez.Device1") || state["org.bluez.Device1"]["Adapter"].Value() != adapterPath {
+				continue
+			}
+			address := state.String("org.bluez.Device1", "Address")
+			alias := state.String("org.bluez.Device1", "Alias")
+			name := state.String("org.bluez.Device1", "Name")
+			switch {
+			case r.result.Device != "":
+				if strings.EqualFold(address, r.result.Device) {
+					exact = append(exact, address)
+				}
+			case strings.EqualFold(r.deviceName, alias) || strings.EqualFold(r.deviceName, name):
+				exact = append(exact, address)
+			case similar(r.deviceName, alias) || similar(r.deviceName, name):
+				fuzzy = append(fuzzy, address)
+			}
+		}
+		return len(exact) > 0 || len(fuzzy) > 0
+	})
+	if err != nil {
+		return stepFailed, "", err
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = fuzzy
+	}
+	if len(matches) > 1 {
+		sort.Strings(matches)
+		return stepFailed, "", withExitCode(exitAmbiguous, errors.Errorf("%d discovered devices match %q: %s, please use a MAC address or a more specific name", len(matches), r.deviceName, strings.Join(matches, ", ")))
+	}
+	r.result.Device = matches[0]
+	return stepDone, "found " + r.result.Device, nil
+}
+
+func (r *autoRun) pair() (string, string, error) {
+	paired, err := r.deviceBool("Paired")
+	if err != nil {
+		return stepFailed, "", err
+	}
+	if paired {
+		return stepSkipped, "already paired", nil
+	}
+	debug("pairing adapter=%s device=%s", r.result.Adapter, r.result.Device)
+	if err := r.b.PairAndWait(r.result.Adapter, r.result.Device, r.policy.AttemptTimeout); err != nil {
+		return stepFailed, "", err
+	}
+	return stepDone, "", nil
+}
+
+func (r *autoRun) trust() (string, string, error) {
+	if noTrust, _ := r.cmd.Flags().GetBool("no-trust"); noTrust {
+		return stepSkipped, "--no-trust is set", nil
+	}
+	trusted, err := r.deviceBool("Trusted")
+	if err != nil {
+		return stepFailed, "", err
+	}
+	if trusted {
+		return stepSkipped, "already trusted", nil
+	}
+	if err := r.b.SetDeviceProperty(r.result.Adapter, r.result.Device, "Trusted", true); err != nil {
+		return stepFailed, "", err
+	}
+	return stepDone, "", nil
+}
+
+func (r *autoRun) connect() (string, string, error) {
+	connected, err := r.deviceBool("Connected")
+	if err != nil {
+		return stepFailed, "", err
+	}
+	if connected {
+		return stepSkipped, "already connected", nil
+	}
+	debug("connecting to adapter=%s device=%s", r.result.Adapter, r.result.Device)
+	if err := r.b.ConnectWithRetry(r.result.Adapter, r.result.Device, r.policy); err != nil {
+		return stepFailed, "", err
+	}
+	return stepDone, "", nil
+}
+
+// isAudioSink reports whether the device can play audio, its services are
+// only known once it has connected.
+func (r *autoRun) isAudioSink() (bool, error) {
+	if err := r.b.PopulateCache(); err != nil {
+		return false, err
+	}
+	d, ok := r.b.FindDevice(r.result.Device)
+	return ok && d.HasUUID(bluez.AudioSinkUUID), nil
+}
+
+func (r *autoRun) profile() (string, string, error) {
+	profile := audioProfileFor(r.cmd, r.result.Device)
+	if profile == "" {
+		return stepSkipped, "no audio profile set", nil
+	}
+	sink, err := r.isAudioSink()
+	if err != nil {
+		return stepWarning, "", err
+	}
+	if !sink {
+		return stepSkipped, "not an audio device", nil
+	}
+	// NOTE: Need to manually set the card profile for pulseaudio, this
+	// _should_ happen already, but for some reason it doesn't always
+	// happen. This tends to happen when the computer has been idle for a
+	// while.
+	name, err := setAudioProfile(r.result.Device, profile)
+	if err != nil {
+		return stepWarning, "", err
+	}
+	return stepDone, name, nil
+}
+
+func (r *autoRun) audio() (string, string, error) {
+	if !audioSwitchFor(r.cmd, r.result.Device) {
+		return stepSkipped, "audio switching is turned off", nil
+	}
+	sink, err := r.isAudioSink()
+	if err != nil {
+		return stepWarning, "", err
+	}
+	if !sink {
+		return stepSkipped, "not an audio device", nil
+	}
+	if err := switchAudio(r.result.Device); err != nil {
+		return stepWarning, "", err
+	}
+	return stepDone, "", nil
 }
 
 func init() {
+	addRetryFlags(autoCmd)
+	autoCmd.Flags().Duration("discover-timeout", 30*time.Second, "How long to wait for a device bluez doesn't know about to be discovered, 0 waits forever")
+	autoCmd.Flags().Bool("no-trust", false, "Don't trust the device after pairing")
+	addAudioFlags(autoCmd)
 	rootCmd.AddCommand(autoCmd)
 }
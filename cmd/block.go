@@ -0,0 +1,46 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// blockCmd represents the block command
+var blockCmd = &cobra.Command{
+	Use:          "block [DEVICE...]",
+	Short:        "Block devices, rejecting any connections from them",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDevicesProperty(cmd, args, "Blocked", true)
+	},
+}
+
+// unblockCmd represents the unblock command
+var unblockCmd = &cobra.Command{
+	Use:          "unblock [DEVICE...]",
+	Short:        "Unblock devices so they can connect again",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDevicesProperty(cmd, args, "Blocked", false)
+	},
+}
+
+func init() {
+	addParallelFlag(blockCmd)
+	addParallelFlag(unblockCmd)
+	rootCmd.AddCommand(blockCmd)
+	rootCmd.AddCommand(unblockCmd)
+}
@@ -0,0 +1,230 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/config"
+)
+
+// cfg is the configuration loaded before any command runs.
+var cfg *config.Config
+
+// cfgErr is why the configuration couldn't be loaded for a config command.
+var cfgErr error
+
+// configFlags are the settings used as the value of the flag of the same
+// name when the flag isn't given.
+var configFlags = []string{"adapter", "output", "audio-profile", "retries", "retry-delay"}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Get, set or list configured defaults",
+	Long: `Config manages the configuration file, which holds defaults for flags and
+settings for particular devices. The file is $XDG_CONFIG_HOME/sluez/config
+unless SLUEZ_CONFIG or --config is given.
+
+Settings are used in this order, the first one that is set wins:
+
+  1. flags, ie: --audio-profile
+  2. environment variables, ie: SLUEZ_AUDIO_PROFILE
+  3. device settings, ie: device.2C:41:A1:49:37:CF.audio-profile
+  4. global settings, ie: audio-profile
+  5. the flag default
+
+Settings:
+` + configKeyUsage(),
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:          "get KEY",
+	Short:        "Print the value of a setting",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		value, source, ok := cfg.Get(args[0])
+		if !ok {
+			return withExitCode(exitNotFound, errors.Errorf("%q is not set", args[0]))
+		}
+		return p.print(config.Setting{Key: args[0], Value: value, Source: source}, func() {
+			fmt.Println(value)
+		})
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:          "set KEY VALUE",
+	Short:        "Change a setting in the configuration file, an empty value removes it",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cfgErr != nil {
+			return cfgErr
+		}
+		if err := cfg.Set(args[0], args[1]); err != nil {
+			if os.IsPermission(err) {
+				return errors.Wrapf(err, "unable to write %s", cfg.Path)
+			}
+			return withExitCode(exitUsage, err)
+		}
+		if _, source, _ := cfg.Get(args[0]); source == config.SourceEnv {
+			fmt.Fprintf(os.Stderr, "%s is overridden by the environment\n", args[0])
+		}
+		return nil
+	},
+}
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List every setting and where it is set",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		settings := cfg.List()
+		return p.print(settings, func() {
+			fmt.Fprintf(os.Stderr, "# %s\n", cfg.Path)
+			for _, s := range settings {
+				fmt.Printf("%s = %s (%s)\n", s.Key, s.Value, s.Source)
+			}
+		})
+	},
+}
+
+func configKeyUsage() string {
+	var b strings.Builder
+	for _, k := range config.Keys {
+		fmt.Fprintf(&b, "  %-14s %s\n", k.Name, k.Usage)
+	}
+	b.WriteString("\nDevice settings, as device.ADDRESS.SETTING:\n")
+	for _, k := range config.DeviceKeys {
+		fmt.Fprintf(&b, "  %-14s %s\n", k.Name, k.Usage)
+	}
+	return b.String()
+}
+
+// isSubcommand returns whether cmd is parent or one of its subcommands.
+func isSubcommand(cmd, parent *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig loads the configuration and uses it for every flag in
+// configFlags that wasn't given. The values are set without marking the
+// flags as changed, so they still read as defaults.
+func loadConfig(cmd *cobra.Command) error {
+	debugging, _ = cmd.Flags().GetBool("debug")
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		err = withExitCode(exitUsage, errors.Wrap(err, "unable to load config"))
+		// The config commands are how a broken file gets looked at, so
+		// they run without it, config set refuses to overwrite it.
+		if !isSubcommand(cmd, configCmd) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		cfgErr = err
+		c = &config.Config{Path: path}
+	}
+	cfg = c
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+
+	for _, name := range configFlags {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		// A configured output format would conflict with --template.
+		if name == "output" && cmd.Flags().Changed("template") {
+			continue
+		}
+		if value, source, ok := cfg.Get(name); ok {
+			debug("using %s=%s from %s", name, value, source)
+			if err := f.Value.Set(value); err != nil {
+				return usageError("invalid %s %q from %s: %v", name, value, source, err)
+			}
+		}
+	}
+	if f := cmd.Flags().Lookup("no-audio-switch"); f != nil && !f.Changed {
+		if value, source, ok := cfg.Get("audio-switch"); ok {
+			debug("using audio-switch=%s from %s", value, source)
+			on, _ := strconv.ParseBool(value)
+			f.Value.Set(strconv.FormatBool(!on))
+		}
+	}
+	return nil
+}
+
+// audioProfileFor returns the audio profile to select for a device, the
+// device setting is used unless --audio-profile was given.
+func audioProfileFor(cmd *cobra.Command, device string) string {
+	profile, _ := cmd.Flags().GetString("audio-profile")
+	if cfg == nil || cmd.Flags().Changed("audio-profile") {
+		return profile
+	}
+	if value, _, ok := cfg.Device(device, "audio-profile"); ok {
+		return value
+	}
+	return profile
+}
+
+// audioSwitchFor reports whether audio output should move to a device, the
+// device setting is used unless --no-audio-switch was given.
+func audioSwitchFor(cmd *cobra.Command, device string) bool {
+	noSwitch, _ := cmd.Flags().GetBool("no-audio-switch")
+	if cfg == nil || cmd.Flags().Changed("no-audio-switch") {
+		return !noSwitch
+	}
+	if value, _, ok := cfg.Device(device, "audio-switch"); ok {
+		on, _ := strconv.ParseBool(value)
+		return on
+	}
+	return !noSwitch
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
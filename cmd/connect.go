@@ -16,46 +16,102 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
-	"strings"
+	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
 )
 
 // connectCmd represents the connect command
 var connectCmd = &cobra.Command{
-	Use:   "connect",
-	Short: "Connect a paired bluetooth device to an adapter",
+	Use:   "connect [DEVICE...]",
+	Short: "Connect paired bluetooth devices to an adapter",
+	Long: `Connect paired bluetooth devices to an adapter.
+
+Devices are given by MAC address, bluez object path, inventory nickname,
+"tag:TAG" or a fragment of their name. Several devices are connected at
+once, up to --parallel, and a result is printed for each of them.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		policy, err := retryPolicy(cmd)
+		if err != nil {
+			return err
+		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
-		device, adapter, err := deviceAndAdapter(b, cmd)
+		devices, adapter, err := devicesAndAdapter(b, cmd, args)
 		if err != nil {
 			return errors.Wrap(err, "unable to determine device and/or adapter")
 		}
-		debug("connecting to adapter=%s device=%s", adapter, device)
-		if err := b.Connect(adapter, device); err != nil {
-			fmt.Printf("unable to connect to device %q: %v\n", device, err)
-			return nil
+		errs, err := runDevices(cmd, devices, func(_ int, device string) error {
+			debug("connecting to adapter=%s device=%s", adapter, device)
+			policy := policy
+			if len(devices) > 1 {
+				policy.OnRetry = reportRetry(device)
+			}
+			return errors.Wrapf(b.ConnectWithRetry(adapter, device, policy), "unable to connect to device %q", device)
+		})
+		if err != nil {
+			return err
 		}
-		fmt.Printf("successfully connected %q and %q\n", device, adapter)
-
-		// NOTE: Need to manually set the card profile for pulseaudio, this _should_
-		// happen already, but for some reason it doesn't always happen. This tends
-		// to happen when the computer has been idle for a while.
-		// pactl set-card-profile bluez_card.2C_41_A1_49_37_CF a2dp_sink
-		// TODO(vishen): pulseaudio can be controlled via dbus, look at using that
-		// instead
-		exec.Command("pactl", "set-card-profile", fmt.Sprintf("bluez_card.%s", strings.Replace(device, ":", "_", -1)), "a2dp_sink").Run()
+		printErr := p.printActions("connect", adapter, devices, errs, func(device string) string {
+			return fmt.Sprintf("successfully connected %q and %q\n", device, adapter)
+		})
 
-		return nil
+		// Audio is switched one device at a time, the last audio device
+		// connected ends up as the default.
+		for i, device := range devices {
+			if errs[i] == nil {
+				connectAudio(b, cmd, device)
+			}
+		}
+		return printErr
 	},
 }
 
+// addRetryFlags adds the flags read by retryPolicy.
+func addRetryFlags(c *cobra.Command) {
+	c.Flags().Int("retries", bluez.DefaultRetryPolicy.Attempts-1, "How many times to retry when the device doesn't respond or is busy")
+	c.Flags().Duration("retry-delay", bluez.DefaultRetryPolicy.Delay, "Delay before the first retry, doubled for each later retry")
+}
+
+// retryPolicy returns the retry policy set by the flags of addRetryFlags,
+// retries are reported on stderr.
+func retryPolicy(cmd *cobra.Command) (bluez.RetryPolicy, error) {
+	policy := bluez.DefaultRetryPolicy
+	retries, _ := cmd.Flags().GetInt("retries")
+	if retries < 0 {
+		return policy, usageError("--retries can't be negative")
+	}
+	policy.Attempts = retries + 1
+	policy.Delay, _ = cmd.Flags().GetDuration("retry-delay")
+	policy.OnRetry = reportRetry("")
+	return policy, nil
+}
+
+// reportRetry returns an OnRetry func printing each retry on stderr,
+// prefixed with device unless it is "".
+func reportRetry(device string) func(attempt int, err error, delay time.Duration) {
+	prefix := ""
+	if device != "" {
+		prefix = device + ": "
+	}
+	return func(attempt int, err error, delay time.Duration) {
+		fmt.Fprintf(os.Stderr, "%sattempt %d failed: %s, retrying in %s\n", prefix, attempt, err, delay.Round(100*time.Millisecond))
+	}
+}
+
 func init() {
+	addRetryFlags(connectCmd)
+	addParallelFlag(connectCmd)
+	addAudioFlags(connectCmd)
 	rootCmd.AddCommand(connectCmd)
 }
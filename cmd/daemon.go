@@ -0,0 +1,400 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// daemonTick is how often the daemon checks whether a device is due to be
+// reconnected.
+const daemonTick = time.Second
+
+// daemonReload is how often the daemon reloads the state of every object
+// from bluez, in case signals arrived out of order.
+const daemonReload = 10 * time.Second
+
+// daemonEvent is printed for everything the daemon notices or does.
+type daemonEvent struct {
+	Time   time.Time `json:"time"`
+	Device string    `json:"device,omitempty"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon [DEVICE...]",
+	Short: "Keep devices connected, reconnecting them when they come back",
+	Long: `Daemon keeps the given devices connected until it is stopped. A device is
+reconnected when it comes into range, when the adapter is powered back on
+and after it disconnects unexpectedly. Failed attempts are retried with a
+growing delay, separately for each device, and no attempts are made during
+--quiet-hours.
+
+A device disconnected on purpose from this machine, ie: with 'sluez
+disconnect', is left alone until it connects again or the adapter is
+powered back on. This needs bluez 5.72 or newer.
+
+Devices must already be paired, 'sluez auto' can pair them. The configured
+favorites are used when no devices are given.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, _ := cmd.Flags().GetString("device")
+		deviceName, _ := cmd.Flags().GetString("device-name")
+		if len(args) == 0 && device == "" && deviceName == "" {
+			args = cfg.Favorites()
+		}
+		if len(args) == 0 && device == "" && deviceName == "" {
+			return usageError("no devices given, give at least one device to keep connected or configure favorites")
+		}
+		quiet, err := parseQuietHours(cmd)
+		if err != nil {
+			return err
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		devices, adapter, err := devicesAndAdapter(b, cmd, args)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+
+		policy := bluez.DefaultRetryPolicy
+		policy.Attempts = 1
+		policy.Delay, _ = cmd.Flags().GetDuration("retry-delay")
+		policy.MaxDelay, _ = cmd.Flags().GetDuration("max-retry-delay")
+		d := &daemon{
+			b:           b,
+			cmd:         cmd,
+			p:           p,
+			adapter:     adapter,
+			adapterPath: dbus.ObjectPath("/org/bluez/" + adapter),
+			policy:      policy,
+			quiet:       quiet,
+			results:     make(chan connectResult, len(devices)),
+		}
+		for _, device := range devices {
+			d.devices = append(d.devices, &daemonDevice{address: device, path: b.DevicePath(adapter, device)})
+		}
+		return d.run()
+	},
+}
+
+// daemon holds the state of the daemon command, everything but the connect
+// attempts runs on one goroutine.
+type daemon struct {
+	b           *bluez.Bluez
+	cmd         *cobra.Command
+	p           *printer
+	adapter     string
+	adapterPath dbus.ObjectPath
+	policy      bluez.RetryPolicy
+	quiet       *quietHours
+
+	w       *bluez.ObjectWatcher
+	powered bool
+	devices []*daemonDevice
+	results chan connectResult
+}
+
+// daemonDevice is a device kept connected by the daemon.
+type daemonDevice struct {
+	address string
+	path    dbus.ObjectPath
+
+	connected  bool
+	connecting bool
+	// held is set when the device was disconnected from this host, it
+	// isn't reconnected until it connects again.
+	held bool
+	// failures is the number of attempts that failed in a row, the next
+	// attempt is made at next.
+	failures int
+	next     time.Time
+	last     time.Time
+}
+
+type connectResult struct {
+	device *daemonDevice
+	err    error
+}
+
+func (d *daemon) run() error {
+	w, err := d.b.WatchObjects()
+	if err != nil {
+		return errors.Wrap(err, "unable to watch bluez")
+	}
+	defer w.Stop()
+	d.w = w
+	d.powered = d.adapterState().Bool("org.bluez.Adapter1", "Powered")
+	for _, dev := range d.devices {
+		dev.connected = d.deviceState(dev).Bool("org.bluez.Device1", "Connected")
+		switch {
+		case !d.deviceState(dev).Has("org.bluez.Device1"):
+			d.event(dev, "unknown", "bluez doesn't know the device yet, pair it with 'sluez auto'", nil)
+		case dev.connected:
+			d.event(dev, "connected", "", nil)
+		}
+	}
+	d.p.info("keeping %d devices connected on %q, press ctrl-c to stop\n", len(d.devices), d.adapter)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(daemonTick)
+	defer ticker.Stop()
+	reload := time.NewTicker(daemonReload)
+	defer reload.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case s := <-w.C:
+			d.handleSignal(s)
+		case r := <-d.results:
+			d.handleResult(r)
+		case <-reload.C:
+			if err := w.Reload(); err != nil {
+				debug("unable to reload bluez objects: %v", err)
+			} else {
+				d.updateState(time.Now())
+			}
+		case <-ticker.C:
+		}
+		d.reconnect()
+	}
+}
+
+func (d *daemon) adapterState() bluez.ObjectState {
+	return d.w.Objects[d.adapterPath]
+}
+
+func (d *daemon) deviceState(dev *daemonDevice) bluez.ObjectState {
+	return d.w.Objects[dev.path]
+}
+
+func (d *daemon) findDevice(path dbus.ObjectPath) *daemonDevice {
+	for _, dev := range d.devices {
+		if dev.path == path {
+			return dev
+		}
+	}
+	return nil
+}
+
+// event prints something the daemon noticed or did.
+func (d *daemon) event(dev *daemonDevice, event, detail string, err error) {
+	e := daemonEvent{Time: time.Now(), Event: event, Detail: detail}
+	if dev != nil {
+		e.Device = dev.address
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	printErr := d.p.print(e, func() {
+		line := e.Time.Format("2006-01-02 15:04:05") + " "
+		if e.Device != "" {
+			line += e.Device + " "
+		}
+		line += event
+		if detail != "" {
+			line += ", " + detail
+		}
+		if e.Error != "" {
+			line += ": " + e.Error
+		}
+		fmt.Println(line)
+	})
+	if printErr != nil {
+		fmt.Fprintf(os.Stderr, "unable to print event: %v\n", printErr)
+	}
+}
+
+func (d *daemon) handleSignal(s *dbus.Signal) {
+	now := time.Now()
+	if reason, ok := bluez.Disconnected(s); ok {
+		if dev := d.findDevice(s.Path); dev != nil && reason == bluez.DisconnectLocal {
+			dev.held = true
+			d.event(dev, "held", "disconnected by this host, not reconnecting until it connects again", nil)
+		}
+		return
+	}
+	// A device reporting its signal strength or being added again is in
+	// range, so it is worth trying straight away. Devices that have been
+	// failing keep their backoff as they report their strength constantly
+	// during discovery.
+	if dev := d.findDevice(s.Path); dev != nil && !dev.connected && dev.failures == 0 {
+		if _, changed, ok := bluez.PropertiesChanged(s); ok && changed["RSSI"].Value() != nil && now.Sub(dev.last) > d.policy.Delay {
+			debug("device %s is in range", dev.address)
+			dev.next = now
+		}
+	}
+	if s.Name == "org.freedesktop.DBus.ObjectManager.InterfacesAdded" && len(s.Body) > 0 {
+		if path, ok := s.Body[0].(dbus.ObjectPath); ok {
+			if dev := d.findDevice(path); dev != nil {
+				d.event(dev, "added", "", nil)
+				dev.next = now
+			}
+		}
+	}
+	if d.w.Update(s) {
+		d.updateState(now)
+	}
+}
+
+// updateState reports changes in the state of the adapter and devices
+// since it was last called.
+func (d *daemon) updateState(now time.Time) {
+	powered := d.adapterState().Bool("org.bluez.Adapter1", "Powered")
+	if powered != d.powered {
+		d.powered = powered
+		if powered {
+			d.event(nil, "powered", d.adapter, nil)
+			for _, dev := range d.devices {
+				dev.held, dev.failures, dev.next = false, 0, now
+			}
+		} else {
+			d.event(nil, "unpowered", d.adapter, nil)
+		}
+	}
+	for _, dev := range d.devices {
+		connected := d.deviceState(dev).Bool("org.bluez.Device1", "Connected")
+		if connected == dev.connected {
+			continue
+		}
+		dev.connected = connected
+		if connected {
+			dev.held, dev.failures = false, 0
+			d.event(dev, "connected", "", nil)
+			continue
+		}
+		d.event(dev, "disconnected", "", nil)
+		// Wait a moment, bluez reports why the device disconnected after
+		// the change in state.
+		dev.next = now.Add(d.policy.Delay)
+	}
+}
+
+func (d *daemon) handleResult(r connectResult) {
+	dev := r.device
+	dev.connecting = false
+	if r.err == nil {
+		// Give bluez a moment to report the device as connected.
+		dev.failures = 0
+		dev.next = time.Now().Add(d.policy.Delay)
+		return
+	}
+	dev.failures++
+	delay := d.policy.Backoff(dev.failures)
+	dev.next = time.Now().Add(delay)
+	d.event(dev, "failed", fmt.Sprintf("retrying in %s", delay.Round(time.Second)), r.err)
+}
+
+// reconnect starts connecting every device that is due, connecting is done
+// in the background and the result sent to d.results.
+func (d *daemon) reconnect() {
+	now := time.Now()
+	if !d.powered || d.quiet.contains(now) {
+		return
+	}
+	for _, dev := range d.devices {
+		state := d.deviceState(dev)
+		if dev.connected || dev.connecting || dev.held || now.Before(dev.next) || !state.Bool("org.bluez.Device1", "Paired") {
+			continue
+		}
+		uuids, _ := state["org.bluez.Device1"]["UUIDs"].Value().([]string)
+		audio := bluez.Device{UUIDs: uuids}.HasUUID(bluez.AudioSinkUUID)
+		dev.connecting = true
+		dev.last = now
+		d.event(dev, "connecting", "", nil)
+		go func(dev *daemonDevice) {
+			err := d.b.ConnectWithRetry(d.adapter, dev.address, d.policy)
+			if err == nil && audio {
+				setupAudio(d.cmd, dev.address)
+			}
+			d.results <- connectResult{device: dev, err: err}
+		}(dev)
+	}
+}
+
+// quietHours is a daily period in local time, as offsets from midnight,
+// when devices aren't reconnected. An end before the start wraps past
+// midnight.
+type quietHours struct {
+	start, end time.Duration
+}
+
+// parseQuietHours parses --quiet-hours, ie: "23:00-07:00", nil is returned
+// when it isn't set.
+func parseQuietHours(cmd *cobra.Command) (*quietHours, error) {
+	value, _ := cmd.Flags().GetString("quiet-hours")
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return nil, usageError("%q is an invalid --quiet-hours, expected START-END, ie: 23:00-07:00", value)
+	}
+	q := &quietHours{}
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, usageError("%q is an invalid --quiet-hours time, expected HH:MM", part)
+		}
+		offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			q.start = offset
+		} else {
+			q.end = offset
+		}
+	}
+	return q, nil
+}
+
+func (q *quietHours) contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if q.start <= q.end {
+		return offset >= q.start && offset < q.end
+	}
+	return offset >= q.start || offset < q.end
+}
+
+func init() {
+	daemonCmd.Flags().String("quiet-hours", "", "Daily period during which devices aren't reconnected, ie: '23:00-07:00'")
+	daemonCmd.Flags().Duration("retry-delay", 5*time.Second, "Delay before retrying a device that failed to connect, doubled for each failure in a row")
+	daemonCmd.Flags().Duration("max-retry-delay", 10*time.Minute, "Longest delay between attempts to connect a device")
+	addAudioFlags(daemonCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
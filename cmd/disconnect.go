@@ -23,28 +23,39 @@ import (
 
 // disconnectCmd represents the disconnect command
 var disconnectCmd = &cobra.Command{
-	Use:   "disconnect",
-	Short: "Disconnect a device from an adapter",
+	Use:   "disconnect [DEVICE...]",
+	Short: "Disconnect devices from an adapter",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
-		device, adapter, err := deviceAndAdapter(b, cmd)
+		devices, adapter, err := devicesAndAdapter(b, cmd, args)
 		if err != nil {
 			return errors.Wrap(err, "unable to determine device and/or adapter")
 		}
-		debug("disconnecting to adapter=%s device=%s", adapter, device)
-		if err := b.Disconnect(adapter, device); err != nil {
-			fmt.Printf("unable to disconnect to device %q: %v", device, err)
-			return nil
+		for _, device := range devices {
+			disconnectAudio(cmd, device)
+		}
+		errs, err := runDevices(cmd, devices, func(_ int, device string) error {
+			debug("disconnecting to adapter=%s device=%s", adapter, device)
+			return errors.Wrapf(b.Disconnect(adapter, device), "unable to disconnect from device %q", device)
+		})
+		if err != nil {
+			return err
 		}
-		fmt.Printf("successfully disconnected %q and %q\n", device, adapter)
-		return nil
+		return p.printActions("disconnect", adapter, devices, errs, func(device string) string {
+			return fmt.Sprintf("successfully disconnected %q and %q\n", device, adapter)
+		})
 	},
 }
 
 func init() {
+	disconnectCmd.Flags().Bool("no-audio-switch", false, "Don't restore the audio output that was the default before the device was connected")
+	addParallelFlag(disconnectCmd)
 	rootCmd.AddCommand(disconnectCmd)
 }
@@ -20,8 +20,18 @@ import (
 	"github.com/godbus/dbus"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
 )
 
+// deviceEvent is printed for each device seen while watching for devices,
+// Event is "known" for devices bluez already knew about and "added" for
+// newly discovered devices, monitor-adv uses "found" and "lost".
+type deviceEvent struct {
+	Event  string       `json:"event"`
+	Device bluez.Device `json:"device"`
+}
+
 // discoverCmd represents the discover command
 var discoverCmd = &cobra.Command{
 	Use:   "discover",
@@ -29,28 +39,37 @@ var discoverCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		adapter, _ := cmd.Flags().GetString("adapter")
 		if adapter == "" {
-			return errors.New("--adapter is required")
+			return usageError("--adapter is required")
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
 		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
 		if err := b.StartDiscovery(adapter); err != nil {
-			fmt.Printf("unable to start discovery: %v", err)
-			return nil
+			return errors.Wrap(err, "unable to start discovery")
 		}
 
-		fmt.Println("Adapters:")
-		for i, a := range b.Adapters {
-			fmt.Printf("%d) name=%q alias=%q address=%q discoverable=%t pairable=%t powered=%t discovering=%t\n", i+1, a.Name, a.Alias, a.Address, a.Discoverable, a.Pairable, a.Powered, a.Discovering)
+		if !p.structured() {
+			fmt.Println("Adapters:")
+			for i, a := range b.Adapters {
+				fmt.Printf("%d) name=%q alias=%q address=%q discoverable=%t pairable=%t powered=%t discovering=%t\n", i+1, a.Name, a.Alias, a.Address, a.Discoverable, a.Pairable, a.Powered, a.Discovering)
+			}
+			fmt.Println("Connected devices:")
 		}
-		fmt.Println("Connected devices:")
 		for i, d := range b.Devices {
-			fmt.Printf("%d) name=%q alias=%q address=%q, adapter=%q paired=%t connected=%t trusted=%t blocked=%t\n", i+1, d.Name, d.Alias, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+			err := p.print(deviceEvent{Event: "known", Device: d}, func() {
+				fmt.Printf("%d) name=%q alias=%q address=%q, adapter=%q paired=%t connected=%t trusted=%t blocked=%t\n", i+1, d.Name, d.Alias, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+			})
+			if err != nil {
+				return err
+			}
 		}
 
-		fmt.Printf("watching for new bluetooth events, make sure to put device into pairing mode\n")
+		p.info("watching for new bluetooth events, make sure to put device into pairing mode\n")
 		signalChan := b.WatchSignal()
 		for signal := range signalChan {
 			debug("received signal=%s => (%d)%v\n", signal.Name, len(signal.Body), signal.Body)
@@ -70,7 +89,12 @@ var discoverCmd = &cobra.Command{
 				}
 				devices := b.ConvertToDevices(string(devicePath), deviceMap)
 				for _, d := range devices {
-					fmt.Printf("name=%q alias=%q address=%q, adapter=%q paired=%t connected=%t trusted=%t blocked=%t\n", d.Name, d.Alias, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+					err := p.print(deviceEvent{Event: "added", Device: d}, func() {
+						fmt.Printf("name=%q alias=%q address=%q, adapter=%q paired=%t connected=%t trusted=%t blocked=%t\n", d.Name, d.Alias, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+					})
+					if err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -0,0 +1,175 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// Exit codes, these are documented in the README and must not change.
+const (
+	exitOK = 0
+	// exitFailure is used for any error that isn't classified below.
+	exitFailure = 1
+	// exitUsage is an invalid command, flag or argument.
+	exitUsage = 2
+	// exitNotFound is a device or adapter that bluez doesn't know about.
+	exitNotFound = 3
+	// exitAmbiguous is a device selection that matched more than one
+	// device, or no selection when there is no terminal to ask on.
+	exitAmbiguous = 4
+	// exitBluez is bluez rejecting an operation, ie: org.bluez.Error.Failed.
+	exitBluez = 5
+	// exitInProgress is an operation already in progress or already done.
+	exitInProgress = 6
+	// exitAuthentication is pairing or authentication failing.
+	exitAuthentication = 7
+	// exitNotReady is an adapter that is powered off or blocked.
+	exitNotReady = 8
+	// exitTimeout is a device not responding in time.
+	exitTimeout = 9
+	// exitPermission is missing permission to perform an operation.
+	exitPermission = 10
+	// exitNoBluetoothd is bluetoothd, or the system bus, not running.
+	exitNoBluetoothd = 11
+)
+
+// exitError sets the exit code of an error returned by a command.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the underlying error, so it can be unwrapped by
+// errors.Cause.
+func (e *exitError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the underlying error for the standard errors package.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with an exit code, wrapping it with further
+// context keeps the exit code.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// usageError returns a formatted error that exits with exitUsage.
+func usageError(format string, args ...interface{}) error {
+	return withExitCode(exitUsage, errors.Errorf(format, args...))
+}
+
+// bluezExitCodes classifies the errors bluez replies with, any other bluez
+// error exits with exitBluez.
+var bluezExitCodes = map[error]int{
+	bluez.ErrNotRunning:              exitNoBluetoothd,
+	bluez.ErrAccessDenied:            exitPermission,
+	bluez.ErrDoesNotExist:            exitNotFound,
+	bluez.ErrInProgress:              exitInProgress,
+	bluez.ErrAlreadyConnected:        exitInProgress,
+	bluez.ErrAlreadyExists:           exitInProgress,
+	bluez.ErrAuthenticationFailed:    exitAuthentication,
+	bluez.ErrAuthenticationCanceled:  exitAuthentication,
+	bluez.ErrAuthenticationRejected:  exitAuthentication,
+	bluez.ErrAuthenticationTimeout:   exitAuthentication,
+	bluez.ErrConnectionAttemptFailed: exitTimeout,
+	bluez.ErrPageTimeout:             exitTimeout,
+	bluez.ErrNotReady:                exitNotReady,
+	bluez.ErrNotAvailable:            exitNotReady,
+}
+
+// exitCode returns the exit code for an error returned by a command, the
+// first classified error in the chain of causes is used.
+func exitCode(err error) int {
+	for err != nil {
+		switch e := err.(type) {
+		case *exitError:
+			return e.code
+		case *bluez.Error:
+			return bluezExitCode(e)
+		case dbus.Error, *dbus.Error:
+			// Replies from services other than bluez, ie: obexd.
+			if be, ok := bluez.ConvertError(err).(*bluez.Error); ok {
+				return bluezExitCode(be)
+			}
+			if name := dbusErrorName(err); name == "org.freedesktop.DBus.Error.NoReply" || name == "org.freedesktop.DBus.Error.Timeout" {
+				return exitTimeout
+			}
+			return exitFailure
+		case interface{ Timeout() bool }:
+			if e.Timeout() {
+				return exitTimeout
+			}
+		}
+		if os.IsPermission(err) {
+			return exitPermission
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return exitFailure
+}
+
+func bluezExitCode(e *bluez.Error) int {
+	if code, ok := bluezExitCodes[e.Err]; ok {
+		return code
+	}
+	return exitBluez
+}
+
+func dbusErrorName(err error) string {
+	switch e := err.(type) {
+	case dbus.Error:
+		return e.Name
+	case *dbus.Error:
+		return e.Name
+	}
+	return ""
+}
+
+// markUsageErrors makes argument validation and flag parsing errors of
+// every command exit with exitUsage.
+func markUsageErrors(c *cobra.Command) {
+	if validate := c.Args; validate != nil {
+		c.Args = func(cmd *cobra.Command, args []string) error {
+			return withExitCode(exitUsage, validate(cmd, args))
+		}
+	}
+	c.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+	for _, child := range c.Commands() {
+		markUsageErrors(child)
+	}
+}
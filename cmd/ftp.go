@@ -0,0 +1,145 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+// ftpCmd represents the ftp command
+var ftpCmd = &cobra.Command{
+	Use:   "ftp",
+	Short: "Browse and transfer files on a device using obex file transfer",
+}
+
+var ftpLsCmd = &cobra.Command{
+	Use:          "ls [FOLDER [DEVICE]]",
+	Short:        "List the contents of a folder on a device",
+	Args:         cobra.MaximumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		c, session, err := obexSession(cmd, deviceArgs(args, 1), obex.TargetFileTransfer)
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		if len(args) > 0 {
+			if err := changeRemoteFolder(c, session, args[0]); err != nil {
+				return err
+			}
+		}
+		entries, err := c.ListFolder(session)
+		if err != nil {
+			return errors.Wrap(err, "unable to list folder")
+		}
+		return p.print(entries, func() {
+			for _, e := range entries {
+				fmt.Printf("type=%s size=%d modified=%q name=%q\n", e.Type, e.Size, e.Modified, e.Name)
+			}
+		})
+	},
+}
+
+var ftpGetCmd = &cobra.Command{
+	Use:          "get REMOTE [LOCAL [DEVICE]]",
+	Short:        "Copy a file from a device",
+	Args:         cobra.RangeArgs(1, 3),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		remote := args[0]
+		local := path.Base(remote)
+		if len(args) > 1 && args[1] != "" {
+			local = args[1]
+		}
+		c, session, err := obexSession(cmd, deviceArgs(args, 2), obex.TargetFileTransfer)
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		if err := changeRemoteFolder(c, session, path.Dir(remote)); err != nil {
+			return err
+		}
+		debug("getting %q from %q to %q", remote, session.Destination, local)
+		if _, err := c.GetFile(session, path.Base(remote), local, printProgress); err != nil {
+			return errors.Wrapf(err, "unable to get %q", remote)
+		}
+		return p.print(transferResult{Device: session.Destination, Direction: "get", Local: local, Remote: remote}, func() {
+			fmt.Printf("successfully copied %q from %q to %q\n", remote, session.Destination, local)
+		})
+	},
+}
+
+var ftpPutCmd = &cobra.Command{
+	Use:          "put LOCAL [REMOTE [DEVICE]]",
+	Short:        "Copy a file to a device",
+	Args:         cobra.RangeArgs(1, 3),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		local := args[0]
+		remote := path.Base(local)
+		if len(args) > 1 && args[1] != "" {
+			remote = args[1]
+		}
+		c, session, err := obexSession(cmd, deviceArgs(args, 2), obex.TargetFileTransfer)
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		if err := changeRemoteFolder(c, session, path.Dir(remote)); err != nil {
+			return err
+		}
+		debug("putting %q to %q on %q", local, remote, session.Destination)
+		if _, err := c.PutFile(session, local, path.Base(remote), printProgress); err != nil {
+			return errors.Wrapf(err, "unable to put %q", local)
+		}
+		return p.print(transferResult{Device: session.Destination, Direction: "put", Local: local, Remote: remote}, func() {
+			fmt.Printf("successfully copied %q to %q on %q\n", local, remote, session.Destination)
+		})
+	},
+}
+
+// transferResult is a file copied to or from a device.
+type transferResult struct {
+	Device    string `json:"device"`
+	Direction string `json:"direction"`
+	Local     string `json:"local"`
+	Remote    string `json:"remote,omitempty"`
+}
+
+func init() {
+	ftpCmd.AddCommand(ftpLsCmd, ftpGetCmd, ftpPutCmd)
+	rootCmd.AddCommand(ftpCmd)
+}
@@ -0,0 +1,194 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/inventory"
+)
+
+// inv is the inventory loaded before any command runs.
+var inv = &inventory.Inventory{}
+
+// invErr is why the inventory couldn't be loaded for an inventory command.
+var invErr error
+
+// inventoryCmd represents the inventory command
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Manage local nicknames, tags and notes for devices",
+	Long: `Inventory keeps nicknames, tags and notes for devices in a local file,
+$XDG_CONFIG_HOME/sluez/inventory.json unless SLUEZ_INVENTORY is set. Unlike
+'sluez alias' nothing is stored in bluez.
+
+A nickname can be used anywhere a device is given, ie: 'sluez connect
+desk-speaker' or '--device-name=desk-speaker', and 'tag:TAG' selects every
+device tagged TAG where several devices can be given, ie: 'sluez trust
+tag:office'. Tags are free form, ie: 'room=office' or 'owner=sam'.`,
+}
+
+// inventoryAddCmd represents the inventory add command
+var inventoryAddCmd = &cobra.Command{
+	Use:          "add NICKNAME DEVICE",
+	Short:        "Give a device a nickname, or change the nickname of a device",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		notes, _ := cmd.Flags().GetString("notes")
+		if err := inventory.ValidNickname(args[0]); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		device := args[1]
+		if !isMacAddress(device) {
+			b, err := newBluez(cmd)
+			if err != nil {
+				return errors.Wrap(err, "unable to get bluez client")
+			}
+			if device, err = findDevice(b, device); err != nil {
+				return err
+			}
+		}
+		if err := inv.Add(inventory.Entry{Nickname: args[0], Address: device, Tags: tags, Notes: notes}); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if err := saveInventory(); err != nil {
+			return err
+		}
+		e, _ := inv.Find(args[0])
+		return printInventory(cmd, e)
+	},
+}
+
+// inventoryRmCmd represents the inventory rm command
+var inventoryRmCmd = &cobra.Command{
+	Use:          "rm NICKNAME...",
+	Short:        "Remove devices from the inventory",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, nickname := range args {
+			if _, ok := inv.Remove(nickname); !ok {
+				return withExitCode(exitNotFound, errors.Errorf("no device with nickname %q", nickname))
+			}
+		}
+		if err := saveInventory(); err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+// inventoryLsCmd represents the inventory ls command
+var inventoryLsCmd = &cobra.Command{
+	Use:          "ls",
+	Short:        "List the devices in the inventory",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries := inv.Entries
+		if tag, _ := cmd.Flags().GetString("tag"); tag != "" {
+			entries = inv.Tagged(tag)
+		}
+		return printInventory(cmd, entries...)
+	},
+}
+
+// inventoryTagCmd represents the inventory tag command
+var inventoryTagCmd = &cobra.Command{
+	Use:          "tag NICKNAME TAG...",
+	Short:        "Tag a device, or remove tags with --remove",
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remove, _ := cmd.Flags().GetBool("remove")
+		e, err := inv.Tag(args[0], args[1:], remove)
+		if err != nil {
+			return withExitCode(exitNotFound, err)
+		}
+		if err := saveInventory(); err != nil {
+			return err
+		}
+		return printInventory(cmd, e)
+	},
+}
+
+func printInventory(cmd *cobra.Command, entries ...inventory.Entry) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	return p.print(entries, func() {
+		for _, e := range entries {
+			fmt.Printf("%s %s", e.Nickname, e.Address)
+			if len(e.Tags) > 0 {
+				fmt.Printf(" tags=%s", strings.Join(e.Tags, ","))
+			}
+			if e.Notes != "" {
+				fmt.Printf(" notes=%q", e.Notes)
+			}
+			fmt.Println()
+		}
+	})
+}
+
+// loadInventory loads the inventory used to look up nicknames and tags.
+// The inventory commands run with an empty inventory when the file can't be
+// loaded, so it can still be looked at, but nothing is saved over it.
+func loadInventory(cmd *cobra.Command) error {
+	path := inventory.DefaultPath()
+	i, err := inventory.Load(path)
+	if err != nil {
+		err = withExitCode(exitUsage, errors.Wrap(err, "unable to load inventory"))
+		if !isSubcommand(cmd, inventoryCmd) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		invErr = err
+		i = &inventory.Inventory{Path: path, Entries: []inventory.Entry{}}
+	}
+	inv = i
+	return nil
+}
+
+// saveInventory writes the inventory, unless it couldn't be loaded as that
+// would replace the file.
+func saveInventory() error {
+	if invErr != nil {
+		return errors.Wrapf(invErr, "not writing %s, fix or remove it first", inv.Path)
+	}
+	if err := inv.Save(); err != nil {
+		return errors.Wrapf(err, "unable to write %s", inv.Path)
+	}
+	return nil
+}
+
+func init() {
+	inventoryAddCmd.Flags().StringSlice("tag", nil, "Tags for the device, ie: --tag room=office --tag owner=sam")
+	inventoryAddCmd.Flags().String("notes", "", "Notes about the device")
+	inventoryLsCmd.Flags().String("tag", "", "Only list devices with this tag")
+	inventoryTagCmd.Flags().Bool("remove", false, "Remove the tags instead of adding them")
+	inventoryCmd.AddCommand(inventoryAddCmd)
+	inventoryCmd.AddCommand(inventoryRmCmd)
+	inventoryCmd.AddCommand(inventoryLsCmd)
+	inventoryCmd.AddCommand(inventoryTagCmd)
+	rootCmd.AddCommand(inventoryCmd)
+}
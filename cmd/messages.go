@@ -0,0 +1,218 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+// messagesCmd represents the messages command
+var messagesCmd = &cobra.Command{
+	Use:   "messages",
+	Short: "Read and send messages on a phone using map",
+}
+
+var messagesFoldersCmd = &cobra.Command{
+	Use:          "folders [FOLDER [DEVICE]]",
+	Short:        "List message folders, relative to " + obex.MessageRootFolder,
+	Args:         cobra.MaximumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		folder := ""
+		if len(args) > 0 {
+			folder = args[0]
+		}
+		c, session, err := messageSession(cmd, folder, deviceArgs(args, 1))
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		folders, err := c.ListMessageFolders(session)
+		if err != nil {
+			return errors.Wrap(err, "unable to list folders")
+		}
+		return p.print(folders, func() {
+			for _, f := range folders {
+				fmt.Println(f)
+			}
+		})
+	},
+}
+
+var messagesListCmd = &cobra.Command{
+	Use:          "list [FOLDER [DEVICE]]",
+	Short:        "List the messages in a folder, defaults to 'inbox'",
+	Args:         cobra.MaximumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		folder := "inbox"
+		if len(args) > 0 {
+			folder = args[0]
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		c, session, err := messageSession(cmd, "", deviceArgs(args, 1))
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		messages, err := c.ListMessages(session, folder, messageFilter(cmd))
+		if err != nil {
+			return errors.Wrapf(err, "unable to list messages in %q", folder)
+		}
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].Timestamp > messages[j].Timestamp
+		})
+		return p.print(messages, func() {
+			for _, m := range messages {
+				fmt.Printf("handle=%s timestamp=%s type=%s read=%t sender=%q sender_address=%q subject=%q\n", m.Handle, m.Timestamp, m.Type, m.Read, m.Sender, m.SenderAddress, m.Subject)
+			}
+		})
+	},
+}
+
+var messagesGetCmd = &cobra.Command{
+	Use:          "get HANDLE [DEVICE]",
+	Short:        "Download a message by the handle shown by 'messages list'",
+	Args:         cobra.RangeArgs(1, 2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		folder, _ := cmd.Flags().GetString("folder")
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		c, session, err := messageSession(cmd, "", deviceArgs(args, 1))
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		// Message objects only exist once they have been listed in the
+		// session.
+		messages, err := c.ListMessages(session, folder, obex.MessageFilter{})
+		if err != nil {
+			return errors.Wrapf(err, "unable to list messages in %q", folder)
+		}
+		for _, m := range messages {
+			if m.Handle != args[0] {
+				continue
+			}
+			bmsg, err := c.GetMessage(m, false, nil)
+			if err != nil {
+				return errors.Wrapf(err, "unable to get message %q", m.Handle)
+			}
+			return p.print(bmsg, func() {
+				fmt.Println(bmsg.Body)
+			})
+		}
+		return errors.Errorf("no message with handle %q in %q", args[0], folder)
+	},
+}
+
+var messagesPushCmd = &cobra.Command{
+	Use:          "push TEXT [DEVICE]",
+	Short:        "Send an sms through a phone",
+	Args:         cobra.RangeArgs(1, 2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetString("to")
+		if to == "" {
+			return usageError("--to is required")
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		c, session, err := messageSession(cmd, "", deviceArgs(args, 1))
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		debug("pushing message to %q", to)
+		if err := c.PushMessage(session, "outbox", obex.NewSMS(to, args[0]), nil); err != nil {
+			return err
+		}
+		return p.print(messageSent{Device: session.Destination, To: to}, func() {
+			fmt.Printf("successfully sent message to %q\n", to)
+		})
+	},
+}
+
+// messageSent is printed once a message has been pushed to a phone.
+type messageSent struct {
+	Device string `json:"device"`
+	To     string `json:"to"`
+}
+
+// messageSession creates a map session with the device given in args and
+// changes into the message root folder, or a folder below it.
+func messageSession(cmd *cobra.Command, folder string, args []string) (*obex.Client, obex.Session, error) {
+	c, session, err := obexSession(cmd, args, obex.TargetMessage)
+	if err != nil {
+		return nil, session, err
+	}
+	if folder != "" {
+		folder = obex.MessageRootFolder + "/" + folder
+	} else {
+		folder = obex.MessageRootFolder
+	}
+	if err := c.SetMessageFolder(session, folder); err != nil {
+		c.RemoveSession(session)
+		return nil, session, errors.Wrapf(err, "unable to change to folder %q", folder)
+	}
+	return c, session, nil
+}
+
+func messageFilter(cmd *cobra.Command) obex.MessageFilter {
+	f := obex.MessageFilter{}
+	f.UnreadOnly, _ = cmd.Flags().GetBool("unread")
+	f.Sender, _ = cmd.Flags().GetString("sender")
+	f.PeriodBegin, _ = cmd.Flags().GetString("period-begin")
+	f.PeriodEnd, _ = cmd.Flags().GetString("period-end")
+	f.Types, _ = cmd.Flags().GetStringSlice("types")
+	f.Offset, _ = cmd.Flags().GetUint16("offset")
+	f.MaxCount, _ = cmd.Flags().GetUint16("max-count")
+	return f
+}
+
+func init() {
+	messagesListCmd.Flags().Bool("unread", false, "Only list unread messages")
+	messagesListCmd.Flags().String("sender", "", "Only list messages from a sender")
+	messagesListCmd.Flags().String("period-begin", "", "Only list messages after a time, ie: '20180601T000000'")
+	messagesListCmd.Flags().String("period-end", "", "Only list messages before a time, ie: '20180630T235959'")
+	messagesListCmd.Flags().StringSlice("types", nil, "Only list messages of these types, ie: 'sms,mms'")
+	messagesListCmd.Flags().Uint16("offset", 0, "Index of the first message to list")
+	messagesListCmd.Flags().Uint16("max-count", 0, "Largest number of messages to list, 0 uses the phone's default")
+	messagesGetCmd.Flags().String("folder", "inbox", "Folder containing the message")
+	messagesPushCmd.Flags().String("to", "", "Phone number to send the message to")
+	messagesCmd.AddCommand(messagesFoldersCmd, messagesListCmd, messagesGetCmd, messagesPushCmd)
+	rootCmd.AddCommand(messagesCmd)
+}
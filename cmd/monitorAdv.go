@@ -0,0 +1,125 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+const monitorAdvPath = dbus.ObjectPath("/org/sluez/monitor")
+
+// monitorAdvCmd represents the monitor-adv command
+var monitorAdvCmd = &cobra.Command{
+	Use:   "monitor-adv",
+	Short: "Passively watch for LE advertisements matching patterns, without discovery",
+	Long: `Passively watch for LE advertisements matching patterns, without discovery.
+
+Patterns are START:ADTYPE:HEX, the hex content must be found at byte START
+of the advertising data of type ADTYPE. A device matching any pattern is
+reported as found, ie: to match Apple manufacturer data:
+
+	sluez monitor-adv --pattern 0:0xff:4c00`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		adapter, _ := cmd.Flags().GetString("adapter")
+		rawPatterns, _ := cmd.Flags().GetStringSlice("pattern")
+		options := bluez.MonitorOptions{}
+		for _, p := range rawPatterns {
+			pattern, err := bluez.ParseMonitorPattern(p)
+			if err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			options.Patterns = append(options.Patterns, pattern)
+		}
+		if len(options.Patterns) == 0 {
+			return usageError("at least one --pattern is required")
+		}
+		options.RSSIHighThreshold, _ = cmd.Flags().GetInt16("rssi-high")
+		options.RSSILowThreshold, _ = cmd.Flags().GetInt16("rssi-low")
+		options.RSSIHighTimeout, _ = cmd.Flags().GetUint16("rssi-high-timeout")
+		options.RSSILowTimeout, _ = cmd.Flags().GetUint16("rssi-low-timeout")
+		if cmd.Flags().Changed("rssi-sampling-period") {
+			period, _ := cmd.Flags().GetUint16("rssi-sampling-period")
+			options.RSSISamplingPeriod = &period
+		}
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		debug("registering advertisement monitor on adapter=%s patterns=%v", adapter, rawPatterns)
+		monitor, err := b.RegisterAdvertisementMonitor(adapter, monitorAdvPath, options)
+		if err != nil {
+			return errors.Wrap(err, "unable to register advertisement monitor")
+		}
+		p.info("monitoring advertisements on %q, press ctrl-c to stop\n", adapter)
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		for {
+			select {
+			case <-stop:
+				if err := monitor.Unregister(); err != nil {
+					return errors.Wrap(err, "unable to unregister advertisement monitor")
+				}
+				return nil
+			case e := <-monitor.Events():
+				event := "lost"
+				if e.Found {
+					event = "found"
+				}
+				d, err := b.DeviceAt(e.Device)
+				if err != nil {
+					debug("unable to get device properties for %s: %v", e.Device, err)
+					d = bluez.Device{Path: string(e.Device)}
+				}
+				err = p.print(deviceEvent{Event: event, Device: d}, func() {
+					if d.Address == "" {
+						fmt.Printf("%s path=%q\n", event, d.Path)
+						return
+					}
+					fmt.Printf("%s name=%q alias=%q address=%q\n", event, d.Name, d.Alias, d.Address)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	monitorAdvCmd.Flags().StringSliceP("pattern", "p", nil, "Pattern to match as START:ADTYPE:HEX, can be repeated and any pattern matching is enough")
+	monitorAdvCmd.Flags().Int16("rssi-high", bluez.MonitorRSSIUnset, "RSSI in dBm a device must stay above to be found, 127 is unset")
+	monitorAdvCmd.Flags().Int16("rssi-low", bluez.MonitorRSSIUnset, "RSSI in dBm a device must stay below to be lost, 127 is unset")
+	monitorAdvCmd.Flags().Uint16("rssi-high-timeout", 0, "Seconds a device must stay above --rssi-high to be found")
+	monitorAdvCmd.Flags().Uint16("rssi-low-timeout", 0, "Seconds a device must stay below --rssi-low to be lost")
+	monitorAdvCmd.Flags().Uint16("rssi-sampling-period", 0, "Report RSSI every N*100ms, 0 reports every advertisement")
+	rootCmd.AddCommand(monitorAdvCmd)
+}
@@ -0,0 +1,202 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// networkCmd represents the network command
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Share or use an internet connection over bluetooth PAN",
+}
+
+var networkConnectCmd = &cobra.Command{
+	Use:          "connect [DEVICE]",
+	Short:        "Connect to the network of a device, ie: tethering through a phone",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		role, _ := cmd.Flags().GetString("role")
+		detach, _ := cmd.Flags().GetBool("detach")
+		if err := validNetworkRole(role); err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+
+		// Watch before connecting so a disconnect straight after connecting
+		// isn't missed.
+		signalChan, stopWatching := b.WatchPropertiesChanged()
+		defer stopWatching()
+		debug("connecting to network role=%s on adapter=%s device=%s", role, adapter, device)
+		iface, err := b.NetworkConnect(adapter, device, role)
+		if err != nil {
+			return errors.Wrapf(err, "unable to connect to %s network on %q", role, device)
+		}
+		err = p.print(networkEvent{Event: "connected", Adapter: adapter, Device: device, Role: role, Interface: iface}, func() {
+			fmt.Printf("successfully connected to %s network on %q interface=%s\n", role, device, iface)
+		})
+		if err != nil || detach {
+			return err
+		}
+
+		devicePath := b.DevicePath(adapter, device)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		for {
+			select {
+			case <-stop:
+				debug("disconnecting from network on %q", device)
+				if err := b.NetworkDisconnect(adapter, device); err != nil {
+					return errors.Wrapf(err, "unable to disconnect from network on %q", device)
+				}
+				return p.print(networkEvent{Event: "disconnected", Adapter: adapter, Device: device, Role: role}, func() {
+					fmt.Printf("successfully disconnected from network on %q\n", device)
+				})
+			case s := <-signalChan:
+				iface, changed, ok := bluez.PropertiesChanged(s)
+				if !ok || s.Path != devicePath || iface != "org.bluez.Network1" {
+					continue
+				}
+				if connected, ok := changed["Connected"].Value().(bool); ok && !connected {
+					return errors.Errorf("network connection to %q was closed", device)
+				}
+			}
+		}
+	},
+}
+
+var networkDisconnectCmd = &cobra.Command{
+	Use:          "disconnect [DEVICE]",
+	Short:        "Disconnect from the network of a device",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+		if err := b.NetworkDisconnect(adapter, device); err != nil {
+			return errors.Wrapf(err, "unable to disconnect from network on %q", device)
+		}
+		return p.print(networkEvent{Event: "disconnected", Adapter: adapter, Device: device}, func() {
+			fmt.Printf("successfully disconnected from network on %q\n", device)
+		})
+	},
+}
+
+var networkServeCmd = &cobra.Command{
+	Use:          "serve",
+	Short:        "Share this machine's network with devices through a bridge",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		role, _ := cmd.Flags().GetString("role")
+		bridge, _ := cmd.Flags().GetString("bridge")
+		if err := validNetworkRole(role); err != nil {
+			return err
+		}
+		if bridge == "" {
+			return usageError("--bridge is required")
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+
+		debug("registering network server role=%s bridge=%s on adapter=%s", role, bridge, adapter)
+		if err := b.RegisterNetworkServer(adapter, role, bridge); err != nil {
+			return errors.Wrapf(err, "unable to register %s network server", role)
+		}
+		err = p.print(networkEvent{Event: "serving", Adapter: adapter, Role: role, Bridge: bridge}, func() {
+			fmt.Printf("serving %s network on %q through bridge=%s\n", role, adapter, bridge)
+		})
+		if err != nil {
+			return err
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		if err := b.UnregisterNetworkServer(adapter, role); err != nil {
+			return errors.Wrapf(err, "unable to unregister %s network server", role)
+		}
+		return p.print(networkEvent{Event: "stopped", Adapter: adapter, Role: role, Bridge: bridge}, func() {
+			fmt.Printf("stopped serving %s network on %q\n", role, adapter)
+		})
+	},
+}
+
+// networkEvent is printed as network connections are made and closed, and
+// as a network is served.
+type networkEvent struct {
+	Event     string `json:"event"`
+	Adapter   string `json:"adapter"`
+	Device    string `json:"device,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	Bridge    string `json:"bridge,omitempty"`
+}
+
+func validNetworkRole(role string) error {
+	switch role {
+	case bluez.RoleNAP, bluez.RolePANU, bluez.RoleGN:
+		return nil
+	}
+	return usageError("%q is an invalid role, expected nap, panu or gn", role)
+}
+
+func init() {
+	networkConnectCmd.Flags().String("role", bluez.RoleNAP, "Role of the device to connect to: nap, panu or gn")
+	networkConnectCmd.Flags().Bool("detach", false, "Exit once connected instead of disconnecting on exit")
+	networkServeCmd.Flags().String("role", bluez.RoleNAP, "Role to serve: nap, panu or gn")
+	networkServeCmd.Flags().String("bridge", "", "Bridge interface connections are added to, ie: 'br0'")
+	networkCmd.AddCommand(networkConnectCmd, networkDisconnectCmd, networkServeCmd)
+	rootCmd.AddCommand(networkCmd)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+// obexStallTimeout is how long a transfer can go without progress before
+// it is cancelled.
+const obexStallTimeout = 30 * time.Second
+
+// obexSession creates an obex session for target with the device given in
+// args, or the selected device when args is empty. The session must be
+// removed by the caller.
+func obexSession(cmd *cobra.Command, args []string, target string) (*obex.Client, obex.Session, error) {
+	b, err := newBluez(cmd)
+	if err != nil {
+		return nil, obex.Session{}, errors.Wrap(err, "unable to get bluez client")
+	}
+	device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+	if err != nil {
+		return nil, obex.Session{}, errors.Wrap(err, "unable to determine device and/or adapter")
+	}
+	source := ""
+	if a, ok := b.FindAdapter(adapter); ok {
+		source = a.Address
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, obex.Session{}, errors.Wrap(err, "unable to create dbus session bus")
+	}
+	c := obex.NewClient(conn)
+	c.StallTimeout = obexStallTimeout
+	c.Stop = stopOnSignal()
+	debug("creating obex %s session with device=%s source=%s", target, device, source)
+	session, err := c.CreateSession(device, source, target)
+	if err != nil {
+		return nil, obex.Session{}, errors.Wrapf(err, "unable to create %s session with %q", target, device)
+	}
+	return c, session, nil
+}
+
+// stopOnSignal returns a channel that is closed on the first SIGINT or
+// SIGTERM, which cancels the transfer in progress so the command returns
+// and removes its session. A second signal kills the process as usual.
+func stopOnSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		debug("cancelling obex transfer")
+		close(stop)
+	}()
+	return stop
+}
+
+// changeRemoteFolder changes to a folder relative to the current folder of
+// a file transfer session, one level at a time.
+func changeRemoteFolder(c *obex.Client, session obex.Session, folder string) error {
+	if strings.HasPrefix(folder, "/") {
+		if err := c.ChangeFolder(session, ""); err != nil {
+			return errors.Wrap(err, "unable to change to root folder")
+		}
+	}
+	for _, f := range strings.Split(folder, "/") {
+		if f == "" || f == "." {
+			continue
+		}
+		if err := c.ChangeFolder(session, f); err != nil {
+			return errors.Wrapf(err, "unable to change to folder %q", f)
+		}
+	}
+	return nil
+}
+
+// printProgress reports transfer progress on a single line of stderr, it
+// is only shown when stderr is a terminal so that logs aren't spammed.
+func printProgress(t obex.Transfer) {
+	if !isTerminal(os.Stderr) {
+		return
+	}
+	percent := uint64(0)
+	if t.Size > 0 {
+		percent = t.Transferred * 100 / t.Size
+	}
+	fmt.Fprintf(os.Stderr, "\r%s: %s %d/%d bytes (%d%%)", t.Name, t.Status, t.Transferred, t.Size, percent)
+	if t.Status == obex.StatusComplete || t.Status == obex.StatusError {
+		fmt.Fprintln(os.Stderr)
+	}
+}
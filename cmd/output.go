@@ -0,0 +1,161 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by --output.
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputYAML   = "yaml"
+)
+
+// printer writes command results in the format chosen by --output, or
+// through the Go template given with --template.
+type printer struct {
+	w       io.Writer
+	format  string
+	tmpl    *template.Template
+	printed int
+}
+
+func newPrinter(cmd *cobra.Command) (*printer, error) {
+	format, _ := cmd.Flags().GetString("output")
+	text, _ := cmd.Flags().GetString("template")
+	p := &printer{w: os.Stdout, format: format}
+	switch format {
+	case outputText, outputJSON, outputNDJSON, outputYAML:
+	default:
+		return nil, usageError("%q is an invalid output format, expected text, json, ndjson or yaml", format)
+	}
+	if text != "" {
+		if format != outputText {
+			return nil, usageError("--template can't be used with --output")
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, withExitCode(exitUsage, errors.Wrap(err, "unable to parse --template"))
+		}
+		p.tmpl = tmpl
+	}
+	return p, nil
+}
+
+// rawOutputOnly returns a usage error if --output or --template is given
+// to a command whose output is raw data, ie: a serial port bridged to
+// stdout, which can't be printed in another format.
+func rawOutputOnly(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("output") || cmd.Flags().Changed("template") {
+		return usageError("%q writes raw data and doesn't support --output or --template", cmd.CommandPath())
+	}
+	return nil
+}
+
+// structured reports whether results are printed in a machine readable
+// format rather than as text.
+func (p *printer) structured() bool {
+	return p.format != outputText || p.tmpl != nil
+}
+
+// print writes a result. For the text format text is called instead, with
+// json and yaml each call prints a document and with ndjson slices are
+// printed an element per line.
+func (p *printer) print(v interface{}, text func()) error {
+	defer func() { p.printed++ }()
+	switch {
+	case p.tmpl != nil:
+		var b strings.Builder
+		if err := p.tmpl.Execute(&b, v); err != nil {
+			return errors.Wrap(err, "unable to execute --template")
+		}
+		out := b.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		_, err := io.WriteString(p.w, out)
+		return err
+	case p.format == outputJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case p.format == outputNDJSON:
+		enc := json.NewEncoder(p.w)
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			for i := 0; i < rv.Len(); i++ {
+				if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return enc.Encode(v)
+	case p.format == outputYAML:
+		if p.printed > 0 {
+			io.WriteString(p.w, "---\n")
+		}
+		return writeYAML(p.w, v)
+	}
+	text()
+	return nil
+}
+
+// info prints progress messages that aren't part of the result to stderr.
+func (p *printer) info(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// actionResult is the result of a command acting on a device, ie: connect.
+type actionResult struct {
+	Action  string `json:"action"`
+	Device  string `json:"device"`
+	Adapter string `json:"adapter"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// printAction prints the result of an action, text is printed as is for
+// the text format. When the action failed err is returned, the result is
+// only printed for structured formats as Execute prints the error.
+func (p *printer) printAction(action, device, adapter string, err error, text string) error {
+	r := actionResult{Action: action, Device: device, Adapter: adapter, Success: err == nil}
+	if err == nil {
+		return p.print(r, func() { fmt.Print(text) })
+	}
+	r.Error = err.Error()
+	if p.structured() {
+		if printErr := p.print(r, nil); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format: text, json, ndjson or yaml")
+	rootCmd.PersistentFlags().String("template", "", "Go text/template used to print results, ie: '{{range .Devices}}{{.Address}}{{\"\\n\"}}{{end}}'")
+}
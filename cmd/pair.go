@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/godbus/dbus"
 	"github.com/pkg/errors"
@@ -24,19 +25,31 @@ import (
 
 // pairCmd represents the pair command
 var pairCmd = &cobra.Command{
-	Use:   "pair",
+	Use:   "pair [DEVICE]",
 	Short: "Pair a device to an adapter, requires your device to be in pairing mode",
+	Long: `Pair a device to an adapter, requires your device to be in pairing mode.
+
+The device is usually not known to bluez yet, so DEVICE is either an
+inventory nickname, a MAC address or a name matched against the devices
+that are discovered.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		adapter, _ := cmd.Flags().GetString("adapter")
 		if adapter == "" {
-			return errors.New("--adapter is required")
+			return usageError("--adapter is required")
 		}
 		device, _ := cmd.Flags().GetString("device")
 		deviceName, _ := cmd.Flags().GetString("device-name")
+		if len(args) == 1 {
+			device, deviceName = pairTarget(args[0])
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
 
 		// "pair" is different from the rest of the commands as the device
@@ -45,9 +58,8 @@ var pairCmd = &cobra.Command{
 
 		// Check that the device isn't already paired
 		for _, d := range b.Devices {
-			if device != "" && d.Address == device || deviceName != "" && similar(deviceName, d.Name) {
-				fmt.Printf("device %q is already paired\n", d.Name)
-				return nil
+			if device != "" && d.Address == device || deviceName != "" && (similar(deviceName, d.Alias) || similar(deviceName, d.Name)) {
+				return p.printAction("pair", d.Address, adapter, nil, fmt.Sprintf("device %q is already paired\n", d.DisplayName()))
 			}
 		}
 
@@ -55,8 +67,8 @@ var pairCmd = &cobra.Command{
 		if err := b.StartDiscovery(adapter); err != nil {
 			return errors.Wrap(err, "unable to start discovery")
 		}
-		fmt.Printf("found no devices similar to specified device=%s or device-name=%s\n", device, deviceName)
-		fmt.Printf("waiting for new bluetooth devices, make sure to put device into pairing mode\n")
+		p.info("found no devices similar to specified device=%s or device-name=%s\n", device, deviceName)
+		p.info("waiting for new bluetooth devices, make sure to put device into pairing mode\n")
 
 		signalChan := b.WatchSignal()
 		for signal := range signalChan {
@@ -84,10 +96,9 @@ var pairCmd = &cobra.Command{
 						device = d.Address
 						debug("trying to pair with device mac %q", device)
 						if err := b.Pair(adapter, device); err != nil {
-							return errors.Wrapf(err, "unable to pair with device %q", device)
+							return p.printAction("pair", device, adapter, errors.Wrapf(err, "unable to pair with device %q", device), "")
 						}
-						fmt.Printf("successfully paired %q and %q\n", device, adapter)
-						return nil
+						return p.printAction("pair", device, adapter, nil, fmt.Sprintf("successfully paired %q and %q\n", device, adapter))
 					}
 				}
 			}
@@ -96,6 +107,18 @@ var pairCmd = &cobra.Command{
 	},
 }
 
+// pairTarget returns the address or the name of the device to pair given
+// as an arg.
+func pairTarget(arg string) (device, deviceName string) {
+	if e, ok := inv.Find(arg); ok {
+		return e.Address, ""
+	}
+	if isMacAddress(arg) {
+		return strings.ToUpper(arg), ""
+	}
+	return "", arg
+}
+
 func init() {
 	rootCmd.AddCommand(pairCmd)
 }
@@ -0,0 +1,99 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// defaultParallel is how many devices are acted on at once, bluez
+// serialises some operations on an adapter so a few at a time is enough.
+const defaultParallel = 4
+
+// addParallelFlag adds the flag read by runDevices.
+func addParallelFlag(c *cobra.Command) {
+	c.Flags().IntP("parallel", "j", defaultParallel, "How many devices to act on at once when several are given")
+}
+
+// runDevices calls fn with the index of every device, running up to
+// --parallel of them at once. The error of each device is returned in the order of devices.
+func runDevices(cmd *cobra.Command, devices []string, fn func(i int, device string) error) ([]error, error) {
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	if parallel < 1 {
+		return nil, usageError("--parallel must be at least 1")
+	}
+	errs := make([]error, len(devices))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, device string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(i, device)
+		}(i, device)
+	}
+	wg.Wait()
+	return errs, nil
+}
+
+// printActions prints the result of an action on each device. A single
+// device is printed the same as printAction, several are printed as a
+// table for the text format. When any device failed an error counting the
+// failures is returned, with the exit code of the first failure.
+func (p *printer) printActions(action, adapter string, devices []string, errs []error, text func(device string) string) error {
+	if len(devices) == 1 {
+		return p.printAction(action, devices[0], adapter, errs[0], text(devices[0]))
+	}
+
+	results := []actionResult{}
+	var failed []error
+	for i, device := range devices {
+		r := actionResult{Action: action, Device: device, Adapter: adapter, Success: errs[i] == nil}
+		if errs[i] != nil {
+			r.Error = errs[i].Error()
+			failed = append(failed, errs[i])
+		}
+		results = append(results, r)
+	}
+	err := p.print(results, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DEVICE\tRESULT\tDETAIL")
+		for _, r := range results {
+			if r.Success {
+				fmt.Fprintf(w, "%s\tok\t\n", r.Device)
+			} else {
+				fmt.Fprintf(w, "%s\tfailed\t%s\n", r.Device, r.Error)
+			}
+		}
+		w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return withExitCode(exitCode(failed[0]), errors.Errorf("unable to %s %d of %d devices", action, len(failed), len(devices)))
+	}
+	return nil
+}
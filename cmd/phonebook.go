@@ -0,0 +1,139 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+// phonebookCmd represents the phonebook command
+var phonebookCmd = &cobra.Command{
+	Use:   "phonebook",
+	Short: "Download contacts and call history from a phone using pbap",
+}
+
+var phonebookPullCmd = &cobra.Command{
+	Use:          "pull [DEVICE]",
+	Short:        "Download vCards from a phonebook",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		phonebook, _ := cmd.Flags().GetString("phonebook")
+		location, _ := cmd.Flags().GetString("location")
+		vcardVersion, _ := cmd.Flags().GetString("vcard-version")
+		order, _ := cmd.Flags().GetString("order")
+		offset, _ := cmd.Flags().GetUint16("offset")
+		maxCount, _ := cmd.Flags().GetUint16("max-count")
+		fields, _ := cmd.Flags().GetStringSlice("fields")
+		outputFile, _ := cmd.Flags().GetString("output-file")
+
+		switch phonebook {
+		case obex.PhonebookContacts, obex.PhonebookIncoming, obex.PhonebookOutgoing, obex.PhonebookMissed, obex.PhonebookCombined:
+		default:
+			return usageError("%q is an invalid phonebook, expected one of pb, ich, och, mch or cch", phonebook)
+		}
+		switch location {
+		case "internal":
+			location = obex.LocationInternal
+		case "sim":
+			location = obex.LocationSIM
+		default:
+			return usageError("%q is an invalid location, expected internal or sim", location)
+		}
+		switch vcardVersion {
+		case "2.1":
+			vcardVersion = "vcard21"
+		case "3.0":
+			vcardVersion = "vcard30"
+		default:
+			return usageError("%q is an invalid vCard version, expected 2.1 or 3.0", vcardVersion)
+		}
+
+		c, session, err := obexSession(cmd, args, obex.TargetPhonebook)
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		debug("selecting phonebook=%s location=%s", phonebook, location)
+		if err := c.SelectPhonebook(session, location, phonebook); err != nil {
+			return errors.Wrapf(err, "unable to select phonebook %q", phonebook)
+		}
+		data, err := c.PullAll(session, obex.PhonebookFilter{
+			Format:   vcardVersion,
+			Order:    order,
+			Offset:   offset,
+			MaxCount: maxCount,
+			Fields:   fields,
+		}, nil)
+		if err != nil {
+			return errors.Wrap(err, "unable to download phonebook")
+		}
+
+		if outputFile == "" {
+			return printVCards(p, data)
+		}
+		f, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		p.w = f
+		if err := printVCards(p, data); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "successfully downloaded phonebook %q to %q\n", phonebook, outputFile)
+		return nil
+	},
+}
+
+// printVCards prints vCards as downloaded for the text format, other
+// formats print the parsed vCards.
+func printVCards(p *printer, data []byte) error {
+	var err error
+	printErr := p.print(obex.ParseVCards(data), func() {
+		_, err = p.w.Write(data)
+	})
+	if printErr != nil {
+		return printErr
+	}
+	return err
+}
+
+func init() {
+	phonebookPullCmd.Flags().String("phonebook", obex.PhonebookContacts, "Phonebook to download: pb (contacts), ich (incoming calls), och (outgoing calls), mch (missed calls) or cch (all calls)")
+	phonebookPullCmd.Flags().String("location", "internal", "Phonebook location: internal or sim")
+	phonebookPullCmd.Flags().String("vcard-version", "2.1", "vCard version to request: 2.1 or 3.0")
+	phonebookPullCmd.Flags().String("order", "", "Order of the vCards: indexed, alphanumeric or phonetic")
+	phonebookPullCmd.Flags().Uint16("offset", 0, "Index of the first vCard to download")
+	phonebookPullCmd.Flags().Uint16("max-count", 0, "Largest number of vCards to download, 0 downloads all of them")
+	phonebookPullCmd.Flags().StringSlice("fields", nil, "vCard fields to include, ie: 'FN,TEL'")
+	phonebookPullCmd.Flags().String("output-file", "", "Write the phonebook to a file instead of stdout")
+	phonebookCmd.AddCommand(phonebookPullCmd)
+	rootCmd.AddCommand(phonebookCmd)
+}
@@ -0,0 +1,193 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Restrict which services can be used on an adapter",
+	Long: `Restrict which services can be used on an adapter.
+
+Services are given as UUIDs, short UUIDs like 110b, or one of the profile
+groups: ` + strings.Join(profileGroupNames(), ", ") + `.`,
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:          "show",
+	Short:        "Show the allowed services and the devices affected by them",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		uuids, err := b.ServiceAllowList(adapter)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get service allow list for %q", adapter)
+		}
+		r := newPolicyResult(adapter, uuids)
+		r.Devices = []policyDevice{}
+		for _, d := range b.Devices {
+			if d.Adapter != "/org/bluez/"+adapter {
+				continue
+			}
+			affected, err := b.IsAffectedByPolicy(adapter, d.Address)
+			if err != nil {
+				debug("unable to get policy status for %q: %v", d.Address, err)
+				continue
+			}
+			r.Devices = append(r.Devices, policyDevice{Name: d.Name, Address: d.Address, Affected: affected})
+		}
+		return p.print(r, func() {
+			if len(r.Allowed) == 0 {
+				fmt.Printf("adapter=%q allows all services\n", adapter)
+			} else {
+				fmt.Printf("adapter=%q allows:\n", adapter)
+				for _, s := range r.Allowed {
+					if s.Group != "" {
+						fmt.Printf("  %s (%s)\n", s.UUID, s.Group)
+					} else {
+						fmt.Printf("  %s\n", s.UUID)
+					}
+				}
+			}
+			fmt.Println("Devices:")
+			for i, d := range r.Devices {
+				fmt.Printf("%d) name=%q address=%q affected=%t\n", i+1, d.Name, d.Address, d.Affected)
+			}
+		})
+	},
+}
+
+var policyAllowCmd = &cobra.Command{
+	Use:          "allow SERVICE...",
+	Short:        "Only allow the given services or profile groups to be used",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		add, _ := cmd.Flags().GetBool("add")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		if add {
+			current, err := b.ServiceAllowList(adapter)
+			if err != nil {
+				return errors.Wrapf(err, "unable to get service allow list for %q", adapter)
+			}
+			args = append(args, current...)
+		}
+		uuids, err := bluez.ResolveServiceUUIDs(args)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if err := b.SetServiceAllowList(adapter, uuids); err != nil {
+			return errors.Wrapf(err, "unable to set service allow list for %q", adapter)
+		}
+		return p.print(newPolicyResult(adapter, uuids), func() {
+			fmt.Printf("successfully restricted %q to %d services\n", adapter, len(uuids))
+		})
+	},
+}
+
+var policyResetCmd = &cobra.Command{
+	Use:          "reset",
+	Short:        "Allow all services to be used again",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		if err := b.SetServiceAllowList(adapter, []string{}); err != nil {
+			return errors.Wrapf(err, "unable to reset service allow list for %q", adapter)
+		}
+		return p.print(newPolicyResult(adapter, nil), func() {
+			fmt.Printf("successfully allowed all services on %q\n", adapter)
+		})
+	},
+}
+
+// policyResult is the service allow list of an adapter, an empty list
+// allows every service. Devices are only listed by 'policy show'.
+type policyResult struct {
+	Adapter string          `json:"adapter"`
+	Allowed []policyService `json:"allowed"`
+	Devices []policyDevice  `json:"devices,omitempty"`
+}
+
+type policyService struct {
+	UUID  string `json:"uuid"`
+	Group string `json:"group,omitempty"`
+}
+
+type policyDevice struct {
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Affected bool   `json:"affected"`
+}
+
+func newPolicyResult(adapter string, uuids []string) policyResult {
+	r := policyResult{Adapter: adapter, Allowed: []policyService{}}
+	for _, uuid := range uuids {
+		r.Allowed = append(r.Allowed, policyService{UUID: uuid, Group: bluez.ProfileGroupOf(uuid)})
+	}
+	return r
+}
+
+func profileGroupNames() []string {
+	names := []string{}
+	for name := range bluez.ProfileGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	policyAllowCmd.Flags().Bool("add", false, "Add to the currently allowed services instead of replacing them")
+	policyCmd.AddCommand(policyShowCmd)
+	policyCmd.AddCommand(policyAllowCmd)
+	policyCmd.AddCommand(policyResetCmd)
+	rootCmd.AddCommand(policyCmd)
+}
@@ -0,0 +1,29 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// openPTY isn't supported outside of linux, --pty is rejected.
+func openPTY() (master *os.File, slave *os.File, err error) {
+	return nil, nil, fmt.Errorf("pty bridging isn't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+const obexAgentPath = dbus.ObjectPath("/org/sluez/obex/agent")
+
+// receiveCmd represents the receive command
+var receiveCmd = &cobra.Command{
+	Use:          "receive",
+	Short:        "Accept files pushed from devices using obex object push",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		debugging, _ = cmd.Flags().GetBool("debug")
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		dir, _ := cmd.Flags().GetString("dir")
+		allow, _ := cmd.Flags().GetStringSlice("allow")
+		maxSize, _ := cmd.Flags().GetUint64("max-size")
+		extensions, _ := cmd.Flags().GetStringSlice("extensions")
+
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return errors.Errorf("%q is not a directory", dir)
+		}
+
+		conn, err := dbus.SessionBus()
+		if err != nil {
+			return errors.Wrap(err, "unable to create dbus session bus")
+		}
+		receiver := obex.NewClient(conn).NewReceiver(dir, obex.ReceivePolicy{
+			AllowedSenders:    allow,
+			MaxSize:           maxSize,
+			AllowedExtensions: extensions,
+		})
+
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			close(stop)
+		}()
+
+		fmt.Fprintf(os.Stderr, "waiting for files to be pushed into %q\n", dir)
+		// The text format is a JSON record per line as well, so transfers
+		// can always be logged and processed.
+		enc := json.NewEncoder(os.Stdout)
+		err = receiver.Run(obexAgentPath, stop, func(r obex.ReceiveRecord) {
+			if err := p.print(r, func() { enc.Encode(r) }); err != nil {
+				fmt.Fprintf(os.Stderr, "unable to print record: %v\n", err)
+			}
+		})
+		return errors.Wrap(err, "unable to receive files")
+	},
+}
+
+func init() {
+	receiveCmd.Flags().String("dir", ".", "Directory to write received files into")
+	receiveCmd.Flags().StringSlice("allow", nil, "MAC addresses of devices allowed to push files, all devices are allowed if empty")
+	receiveCmd.Flags().Uint64("max-size", 0, "Largest file size accepted in bytes, 0 is no limit")
+	receiveCmd.Flags().StringSlice("extensions", nil, "File extensions accepted, ie: 'jpg,pdf', all extensions are accepted if empty")
+	rootCmd.AddCommand(receiveCmd)
+}
@@ -23,28 +23,35 @@ import (
 
 // removeCmd represents the remove command
 var removeCmd = &cobra.Command{
-	Use:   "remove",
-	Short: "Permanently remove a device from an adapter. A pair is required to reconnect a device.",
+	Use:   "remove [DEVICE...]",
+	Short: "Permanently remove devices from an adapter. A pair is required to reconnect a device.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
-		device, adapter, err := deviceAndAdapter(b, cmd)
+		devices, adapter, err := devicesAndAdapter(b, cmd, args)
 		if err != nil {
 			return errors.Wrap(err, "unable to determine device and/or adapter")
 		}
-		debug("removing adapter=%s device=%s", adapter, device)
-		if err := b.RemoveDevice(adapter, device); err != nil {
-			fmt.Printf("unable to remove to device %q: %v", device, err)
-			return nil
+		errs, err := runDevices(cmd, devices, func(_ int, device string) error {
+			debug("removing adapter=%s device=%s", adapter, device)
+			return errors.Wrapf(b.RemoveDevice(adapter, device), "unable to remove device %q", device)
+		})
+		if err != nil {
+			return err
 		}
-		fmt.Printf("successfully removed %q and %q\n", device, adapter)
-		return nil
+		return p.printActions("remove", adapter, devices, errs, func(device string) string {
+			return fmt.Sprintf("successfully removed %q and %q\n", device, adapter)
+		})
 	},
 }
 
 func init() {
+	addParallelFlag(removeCmd)
 	rootCmd.AddCommand(removeCmd)
 }
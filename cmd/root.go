@@ -17,27 +17,56 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "sluez",
 	Short: "Simple CLI for Bluez dBus on linux",
+	// Errors are printed by Execute so they can be given an exit code.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+		return loadInventory(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Results are printed to stdout and errors to stderr, the exit code
+// describes the kind of error.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	markUsageErrors(rootCmd)
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		os.Exit(exitOK)
+	}
+	code := exitCode(err)
+	// Unknown commands are reported by cobra before any command runs.
+	if strings.HasPrefix(err.Error(), "unknown command") {
+		code = exitUsage
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := bluez.Hint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+	if code == exitUsage {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
 	}
+	os.Exit(code)
 }
 
 func init() {
 	rootCmd.PersistentFlags().Bool("debug", false, "Print debug logs")
+	rootCmd.PersistentFlags().String("config", "", "Configuration file, defaults to $SLUEZ_CONFIG or $XDG_CONFIG_HOME/sluez/config")
 	rootCmd.PersistentFlags().StringP("adapter", "a", "hci0", "HCI device adapter. Can be found from 'hciconfig -a'")
 	rootCmd.PersistentFlags().StringP("device", "d", "", "Bluetooth device MAC address")
 	rootCmd.PersistentFlags().StringP("device-name", "n", "", "Bluetooth device name. A fuzzy search is used to determine which device the name matches for. '--device' will take precedence if both are specified")
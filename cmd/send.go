@@ -0,0 +1,66 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez/obex"
+)
+
+// sendCmd represents the send command
+var sendCmd = &cobra.Command{
+	Use:          "send FILE...",
+	Short:        "Push files to a device using obex object push",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		var devices []string
+		if to, _ := cmd.Flags().GetString("to"); to != "" {
+			devices = []string{to}
+		}
+		c, session, err := obexSession(cmd, devices, obex.TargetObjectPush)
+		if err != nil {
+			return err
+		}
+		defer c.RemoveSession(session)
+
+		for _, file := range args {
+			debug("sending %q to %q", file, session.Destination)
+			if _, err := c.SendFile(session, file, printProgress); err != nil {
+				return errors.Wrapf(err, "unable to send %q", file)
+			}
+			err := p.print(transferResult{Device: session.Destination, Direction: "send", Local: file}, func() {
+				fmt.Printf("successfully sent %q to %q\n", file, session.Destination)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	sendCmd.Flags().String("to", "", "Device to send to, by nickname, MAC address, object path or name")
+	rootCmd.AddCommand(sendCmd)
+}
@@ -0,0 +1,254 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+const serialProfilePath = dbus.ObjectPath("/org/sluez/profile/serial")
+
+// serialCmd represents the serial command
+var serialCmd = &cobra.Command{
+	Use:   "serial",
+	Short: "Talk to devices using the serial port profile (SPP) over RFCOMM",
+}
+
+var serialConnectCmd = &cobra.Command{
+	Use:          "connect [DEVICE]",
+	Short:        "Connect to the serial port of a device and bridge it to stdin/stdout or a pty",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := rawOutputOnly(cmd); err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine device and/or adapter")
+		}
+		profile, err := registerSerialProfile(b, cmd, "client")
+		if err != nil {
+			return err
+		}
+		defer profile.Unregister()
+		local, err := newSerialLocal(cmd)
+		if err != nil {
+			return err
+		}
+		defer local.Close()
+
+		debug("connecting serial port profile on adapter=%s device=%s", adapter, device)
+		if err := b.ConnectProfile(adapter, device, bluez.SerialPortUUID); err != nil {
+			return errors.Wrapf(err, "unable to connect serial port on %q", device)
+		}
+		var conn bluez.ProfileConnection
+		select {
+		case conn = <-profile.Connections():
+		case <-time.After(10 * time.Second):
+			return errors.Errorf("timed out waiting for serial connection to %q", device)
+		}
+		fmt.Fprintf(os.Stderr, "connected to serial port on %q\n", device)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		local.bridge(conn, stop)
+		fmt.Fprintf(os.Stderr, "serial connection to %q closed\n", device)
+		return nil
+	},
+}
+
+var serialListenCmd = &cobra.Command{
+	Use:          "listen",
+	Short:        "Accept serial port connections from devices and bridge them to stdin/stdout or a pty",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := rawOutputOnly(cmd); err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		profile, err := registerSerialProfile(b, cmd, "server")
+		if err != nil {
+			return err
+		}
+		defer profile.Unregister()
+		local, err := newSerialLocal(cmd)
+		if err != nil {
+			return err
+		}
+		defer local.Close()
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		fmt.Fprintf(os.Stderr, "waiting for serial connections\n")
+		for {
+			select {
+			case <-stop:
+				return nil
+			case conn := <-profile.Connections():
+				fmt.Fprintf(os.Stderr, "serial connection from %q\n", conn.Device)
+				if !local.bridge(conn, stop) {
+					return nil
+				}
+				fmt.Fprintf(os.Stderr, "serial connection from %q closed\n", conn.Device)
+				// stdin can't be reused once it has been read to the end.
+				if local.pty == nil {
+					return nil
+				}
+			}
+		}
+	},
+}
+
+func registerSerialProfile(b *bluez.Bluez, cmd *cobra.Command, role string) (*bluez.Profile, error) {
+	channel, _ := cmd.Flags().GetUint16("channel")
+	auth, _ := cmd.Flags().GetBool("require-authentication")
+	profile, err := b.RegisterProfile(serialProfilePath, bluez.SerialPortUUID, bluez.ProfileOptions{
+		Name:                  "sluez serial port",
+		Role:                  role,
+		Channel:               channel,
+		RequireAuthentication: auth,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to register serial port profile")
+	}
+	return profile, nil
+}
+
+// serialLocal is the local end of a serial bridge, either stdin/stdout or
+// a pty. Input is read by a single goroutine so that it can be handed to
+// each connection in turn.
+type serialLocal struct {
+	in      chan []byte
+	out     io.Writer
+	pty     *os.File
+	slave   *os.File
+	symlink string
+}
+
+func newSerialLocal(cmd *cobra.Command) (*serialLocal, error) {
+	symlink, _ := cmd.Flags().GetString("pty")
+	l := &serialLocal{in: make(chan []byte)}
+	var r io.Reader = os.Stdin
+	l.out = os.Stdout
+	if symlink != "" {
+		// Only a link left behind by a previous run is replaced, never a
+		// file the user cares about.
+		if fi, err := os.Lstat(symlink); err == nil {
+			if fi.Mode()&os.ModeSymlink == 0 {
+				return nil, usageError("--pty %q already exists and isn't a symlink", symlink)
+			}
+			if err := os.Remove(symlink); err != nil {
+				return nil, errors.Wrapf(err, "unable to remove %q", symlink)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "unable to check %q", symlink)
+		}
+		master, slave, err := openPTY()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create pty")
+		}
+		if err := os.Symlink(slave.Name(), symlink); err != nil {
+			master.Close()
+			slave.Close()
+			return nil, errors.Wrapf(err, "unable to link %q to %q", symlink, slave.Name())
+		}
+		l.pty, l.slave, l.symlink = master, slave, symlink
+		r, l.out = master, master
+		fmt.Fprintf(os.Stderr, "serial port available at %q (%s)\n", symlink, slave.Name())
+	}
+	go func() {
+		defer close(l.in)
+		for {
+			buf := make([]byte, 4096)
+			n, err := r.Read(buf)
+			if n > 0 {
+				l.in <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return l, nil
+}
+
+// bridge copies data between the local end and a connection until the
+// connection is closed, or stop receives a signal in which case false is
+// returned.
+func (l *serialLocal) bridge(conn bluez.ProfileConnection, stop <-chan os.Signal) bool {
+	defer conn.File.Close()
+	done := make(chan struct{})
+	go func() {
+		io.Copy(l.out, conn.File)
+		close(done)
+	}()
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-done:
+			return true
+		case data, ok := <-l.in:
+			if !ok {
+				// stdin closed, stop sending but keep printing until the
+				// device closes the connection.
+				l.in = nil
+				continue
+			}
+			if _, err := conn.File.Write(data); err != nil {
+				debug("unable to write to serial connection: %v", err)
+				return true
+			}
+		}
+	}
+}
+
+func (l *serialLocal) Close() error {
+	if l.pty == nil {
+		return nil
+	}
+	os.Remove(l.symlink)
+	l.slave.Close()
+	return l.pty.Close()
+}
+
+func init() {
+	for _, c := range []*cobra.Command{serialConnectCmd, serialListenCmd} {
+		c.Flags().Uint16("channel", 0, "RFCOMM channel, 0 uses the channel advertised by the device or lets bluez choose")
+		c.Flags().String("pty", "", "Create a pseudo-terminal linked at this path instead of using stdin/stdout, ie: '/tmp/ttyBT0'")
+		c.Flags().Bool("require-authentication", false, "Require the device to be paired and authenticated")
+	}
+	serialCmd.AddCommand(serialConnectCmd, serialListenCmd)
+	rootCmd.AddCommand(serialCmd)
+}
@@ -16,34 +16,131 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+	"github.com/vishen/sluez/rfkill"
 )
 
+// statusResult is the status of known adapters and devices.
+type statusResult struct {
+	Adapters []adapterStatus `json:"adapters"`
+	Devices  []deviceStatus  `json:"devices"`
+}
+
+type adapterStatus struct {
+	bluez.Adapter
+	Rfkill string `json:"rfkill,omitempty"`
+}
+
+// deviceStatus is a device with its inventory nickname, tags and notes.
+type deviceStatus struct {
+	bluez.Device
+	Nickname   string            `json:"nickname,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+	Transports []transportStatus `json:"transports,omitempty"`
+}
+
+type transportStatus struct {
+	Path      string `json:"path"`
+	State     string `json:"state"`
+	Volume    uint16 `json:"volume"`
+	HasVolume bool   `json:"has_volume"`
+	Codec     string `json:"codec"`
+}
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "The current status of known adapters and devices",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
 		b, err := newBluez(cmd)
 		if err != nil {
-			fmt.Printf("unable to get bluez client: %v\n", err)
-			return nil
+			return errors.Wrap(err, "unable to get bluez client")
 		}
-		fmt.Println("Adapters:")
-		for i, a := range b.Adapters {
-			// TODO(vishen): add these to methods
-			fmt.Printf("%d) name=%q alias=%q address=%q discoverable=%t pairable=%t powered=%t discovering=%t\n", i+1, a.Name, a.Alias, a.Address, a.Discoverable, a.Pairable, a.Powered, a.Discovering)
+
+		status := statusResult{}
+		rfkillDevices, err := rfkill.Devices()
+		if err != nil {
+			debug("unable to read rfkill state: %v", err)
+		}
+		for _, a := range b.Adapters {
+			s := adapterStatus{Adapter: a}
+			for _, r := range rfkillDevices {
+				if r.Type == rfkill.TypeBluetooth && "/org/bluez/"+r.Name == a.Path {
+					s.Rfkill = r.String()
+				}
+			}
+			status.Adapters = append(status.Adapters, s)
 		}
-		fmt.Println("Connected devices:")
-		for i, d := range b.Devices {
-			// TODO(vishen): add these to methods
-			fmt.Printf("%d) name=%q alias=%q address=%q adapter=%q paired=%t connected=%t trusted=%t blocked=%t\n", i+1, d.Name, d.Alias, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+		for _, d := range b.Devices {
+			s := deviceStatus{Device: d}
+			if e, ok := inv.ByAddress(d.Address); ok {
+				s.Nickname, s.Tags, s.Notes = e.Nickname, e.Tags, e.Notes
+			}
+			for _, t := range b.Transports {
+				if !verbose || t.Device != d.Path {
+					continue
+				}
+				codec, err := bluez.DecodeCodecConfiguration(t.Codec, t.Configuration)
+				if err != nil {
+					debug("unable to decode codec configuration for %s: %v", t.Path, err)
+				}
+				s.Transports = append(s.Transports, transportStatus{
+					Path:      t.Path,
+					State:     t.State,
+					Volume:    t.Volume,
+					HasVolume: t.HasVolume,
+					Codec:     codec.String(),
+				})
+			}
+			status.Devices = append(status.Devices, s)
 		}
-		return nil
+
+		return p.print(status, func() {
+			fmt.Println("Adapters:")
+			for i, a := range status.Adapters {
+				fmt.Printf("%d) alias=%q name=%q address=%q discoverable=%t pairable=%t powered=%t discovering=%t", i+1, a.Alias, a.Name, a.Address, a.Discoverable, a.Pairable, a.Powered, a.Discovering)
+				if a.Rfkill != "" {
+					fmt.Printf(" rfkill=%s", a.Rfkill)
+				}
+				fmt.Println()
+			}
+			fmt.Println("Connected devices:")
+			for i, d := range status.Devices {
+				fmt.Printf("%d) alias=%q name=%q address=%q adapter=%q paired=%t connected=%t trusted=%t blocked=%t", i+1, d.Alias, d.Name, d.Address, d.Adapter, d.Paired, d.Connected, d.Trusted, d.Blocked)
+				if d.Nickname != "" {
+					fmt.Printf(" nickname=%q", d.Nickname)
+				}
+				if len(d.Tags) > 0 {
+					fmt.Printf(" tags=%q", strings.Join(d.Tags, ","))
+				}
+				if d.Notes != "" {
+					fmt.Printf(" notes=%q", d.Notes)
+				}
+				fmt.Println()
+				for _, t := range d.Transports {
+					volume := "unsupported"
+					if t.HasVolume {
+						volume = fmt.Sprintf("%d/%d", t.Volume, bluez.MaxVolume)
+					}
+					fmt.Printf("   transport=%q state=%s volume=%s codec=%q\n", t.Path, t.State, volume, t.Codec)
+				}
+			}
+		})
 	},
 }
 
 func init() {
+	statusCmd.Flags().BoolP("verbose", "v", false, "Include audio transport state and codec configuration for devices")
 	rootCmd.AddCommand(statusCmd)
 }
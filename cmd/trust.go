@@ -0,0 +1,158 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// trustCmd represents the trust command
+var trustCmd = &cobra.Command{
+	Use:          "trust [DEVICE...]",
+	Short:        "Trust devices so they can reconnect without authorisation",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDevicesProperty(cmd, args, "Trusted", true)
+	},
+}
+
+// untrustCmd represents the untrust command
+var untrustCmd = &cobra.Command{
+	Use:          "untrust [DEVICE...]",
+	Short:        "Stop trusting devices",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDevicesProperty(cmd, args, "Trusted", false)
+	},
+}
+
+// propertyResult is the result of changing a property of a device.
+type propertyResult struct {
+	Device   string `json:"device"`
+	Property string `json:"property"`
+	Before   bool   `json:"before"`
+	After    bool   `json:"after"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// setDevicesProperty sets a boolean Device1 property on every device given
+// in args, or on the device chosen by the usual flags when there are no
+// args, and reports the state before and after. Devices are changed
+// concurrently and every device is attempted even when one fails.
+func setDevicesProperty(cmd *cobra.Command, args []string, property string, value bool) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	b, err := newBluez(cmd)
+	if err != nil {
+		return errors.Wrap(err, "unable to get bluez client")
+	}
+	devices, adapter, err := devicesAndAdapter(b, cmd, args)
+	if err != nil {
+		return errors.Wrap(err, "unable to determine device and/or adapter")
+	}
+
+	name := strings.ToLower(property)
+	results := make([]propertyResult, len(devices))
+	errs, err := runDevices(cmd, devices, func(i int, device string) error {
+		r, err := setDeviceProperty(b, adapter, device, property, value)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		results[i] = r
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	err = p.print(results, func() {
+		if len(results) > 1 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DEVICE\tBEFORE\tAFTER\tRESULT\tDETAIL")
+			for _, r := range results {
+				if r.Success {
+					fmt.Fprintf(w, "%s\t%t\t%t\tok\t\n", r.Device, r.Before, r.After)
+				} else {
+					fmt.Fprintf(w, "%s\t%t\t-\tfailed\t%s\n", r.Device, r.Before, r.Error)
+				}
+			}
+			w.Flush()
+			return
+		}
+		for _, r := range results {
+			if r.Success {
+				fmt.Printf("%q %s: %t -> %t\n", r.Device, name, r.Before, r.After)
+			} else {
+				fmt.Fprintf(os.Stderr, "unable to set %s=%t on %q: %s\n", name, value, r.Device, r.Error)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return withExitCode(exitCode(failed[0]), errors.Errorf("unable to set %s on %d of %d devices", name, len(failed), len(devices)))
+	}
+	return nil
+}
+
+func setDeviceProperty(b *bluez.Bluez, adapter, device, property string, value bool) (propertyResult, error) {
+	r := propertyResult{Device: device, Property: property}
+	var err error
+	if r.Before, err = devicePropertyBool(b, adapter, device, property); err != nil {
+		return r, err
+	}
+	debug("setting %s=%t on adapter=%s device=%s", property, value, adapter, device)
+	if err := b.SetDeviceProperty(adapter, device, property, value); err != nil {
+		return r, err
+	}
+	if r.After, err = devicePropertyBool(b, adapter, device, property); err != nil {
+		return r, err
+	}
+	r.Success = true
+	return r, nil
+}
+
+func devicePropertyBool(b *bluez.Bluez, adapter, device, property string) (bool, error) {
+	props, err := b.GetDeviceProperties(adapter, device)
+	if err != nil {
+		return false, err
+	}
+	v, _ := props[property].Value().(bool)
+	return v, nil
+}
+
+func init() {
+	addParallelFlag(trustCmd)
+	addParallelFlag(untrustCmd)
+	rootCmd.AddCommand(trustCmd)
+	rootCmd.AddCommand(untrustCmd)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/vishen/sluez/bluez"
+	"github.com/vishen/sluez/inventory"
 )
 
 var (
@@ -22,7 +24,7 @@ func newBluez(cmd *cobra.Command) (*bluez.Bluez, error) {
 	debugging, _ = cmd.Flags().GetBool("debug")
 	conn, err := dbus.SystemBus()
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to create dbus system bus:")
+		return nil, withExitCode(exitNoBluetoothd, errors.Wrap(err, "unable to create dbus system bus"))
 	}
 	b := bluez.NewBluez(conn)
 	if err := b.PopulateCache(); err != nil {
@@ -34,64 +36,285 @@ func newBluez(cmd *cobra.Command) (*bluez.Bluez, error) {
 func deviceAndAdapter(b *bluez.Bluez, cmd *cobra.Command) (device string, adapter string, err error) {
 	adapter, _ = cmd.Flags().GetString("adapter")
 	if adapter == "" {
-		return "", "", errors.New("--adapter is required")
+		return "", "", usageError("--adapter is required")
 	}
 	device, _ = cmd.Flags().GetString("device")
 	deviceName, _ := cmd.Flags().GetString("device-name")
+	if device != "" {
+		return device, adapter, nil
+	}
+	if e, ok := inv.Find(deviceName); ok && deviceName != "" {
+		debug("device name %q is the nickname of %q", deviceName, e.Address)
+		return e.Address, adapter, nil
+	}
 
 	// If no device is specified we will try to grab one from the
-	// cached/known devices.
-	if device == "" || deviceName != "" {
-		debug("no bluetooth mac specified in flags")
-		switch len(b.Devices) {
-		case 0:
-			debug("no bluetooth devices found")
-			return "", "", errors.New("no bluetooth devices found, please specify a --device or --device-name")
-		default:
-			// If a device name was specified, we should check all the connected devices
-			// and if one of them has a similar name to the one specified, use that.
-			if deviceName != "" {
-				for _, d := range b.Devices {
-					if similar(deviceName, d.Name) {
-						device = d.Address
-						// debug("device name matches %q, using %q", d.Name, device)
-						break
-					}
-				}
+	// cached/known devices, the configured favorites first.
+	debug("no bluetooth mac specified in flags")
+	if deviceName == "" {
+		if device, ok := favoriteDevice(b); ok {
+			return device, adapter, nil
+		}
+	}
+	if len(b.Devices) == 0 {
+		debug("no bluetooth devices found")
+		return "", "", withExitCode(exitNotFound, errors.New("no bluetooth devices found, please specify a --device or --device-name"))
+	}
+
+	// If a device name was specified, we should check all the known devices
+	// and if exactly one of them has a similar name to the one specified,
+	// use that.
+	candidates := b.Devices
+	matchCount := 0
+	if deviceName != "" {
+		matches := matchDeviceName(b, deviceName)
+		matchCount = len(matches)
+		if matchCount == 1 {
+			return matches[0].Address, adapter, nil
+		}
+		if matchCount > 1 {
+			candidates = matches
+		}
+	}
+
+	// Ask the user to choose a bluetooth device, which is only possible
+	// when there is someone to ask.
+	if !isTerminal(os.Stdin) {
+		switch {
+		case deviceName != "" && matchCount > 1:
+			return "", "", withExitCode(exitAmbiguous, errors.Errorf("%d devices match %q, please specify a --device or a more specific --device-name", matchCount, deviceName))
+		case deviceName != "":
+			return "", "", withExitCode(exitNotFound, errors.Errorf("no bluetooth device found matching %q", deviceName))
+		}
+		return "", "", withExitCode(exitAmbiguous, errors.New("no device specified, please specify a --device or --device-name"))
+	}
+	device, err = chooseDevice(candidates)
+	return device, adapter, err
+}
+
+// favoriteDevice returns the first configured favorite that bluez knows
+// about, favorites given by name must match exactly one device.
+func favoriteDevice(b *bluez.Bluez) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	for _, favorite := range cfg.Favorites() {
+		if e, ok := inv.Find(favorite); ok {
+			favorite = e.Address
+		}
+		if isMacAddress(favorite) {
+			if d, ok := b.FindDevice(favorite); ok {
+				debug("using favorite device %q", d.Address)
+				return d.Address, true
 			}
+			continue
+		}
+		if matches := matchDeviceName(b, favorite); len(matches) == 1 {
+			debug("using favorite device %q for %q", matches[0].Address, favorite)
+			return matches[0].Address, true
+		}
+	}
+	return "", false
+}
 
-			// If we have found a device from the above searching.
-			if device != "" {
-				break
+// chooseDevice asks the user to choose one of devices on stdin, the
+// choices are printed to stderr.
+func chooseDevice(devices []bluez.Device) (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "Choose a bluetooth device from the following:\n")
+		for i, d := range devices {
+			if d.Name != "" && d.Name != d.DisplayName() {
+				fmt.Fprintf(os.Stderr, "%d) %s (%s), %s\n", i+1, d.DisplayName(), d.Name, d.Address)
+				continue
 			}
+			fmt.Fprintf(os.Stderr, "%d) %s, %s\n", i+1, d.DisplayName(), d.Address)
+		}
+		fmt.Fprintf(os.Stderr, ">> ")
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return "", errors.Wrap(err, "unable to read from stdin")
+		}
+		text = strings.TrimSpace(text)
+		i, err := strconv.Atoi(text)
+		if err != nil || i < 1 || i > len(devices) {
+			fmt.Fprintf(os.Stderr, "'%s' is an invalid choice, please select the number for the device you want to connect\n", text)
+			continue
+		}
+		return devices[i-1].Address, nil
+	}
+}
 
-			// Ask the user to choose a bluetooth device from the connected devices.
-			for {
-				fmt.Printf("Choose a bluetooth device from the following:\n")
-				for i, d := range b.Devices {
-					fmt.Printf("%d) %s, %s\n", i+1, d.Name, d.Address)
-				}
-				fmt.Printf(">> ")
-				reader := bufio.NewReader(os.Stdin)
-				text, err := reader.ReadString('\n')
-				if err != nil {
-					fmt.Printf("unable to read from stdin: %s\n", err)
-					continue
-				}
-				text = strings.TrimSpace(text)
-				i, err := strconv.Atoi(text)
-				if err != nil || i < 1 || i > len(b.Devices) {
-					fmt.Printf("'%s' is an invalid choice, please select the number for the device you want to connect\n", text)
-					continue
+// devicesAndAdapter returns a device for each of args, which are either
+// inventory nicknames, MAC addresses, bluez object paths or names fuzzy
+// matched against known devices. "tag:TAG" is every device in the
+// inventory tagged TAG. A device given more than once is only returned
+// once. With no args the device is chosen the same as deviceAndAdapter.
+func devicesAndAdapter(b *bluez.Bluez, cmd *cobra.Command, args []string) (devices []string, adapter string, err error) {
+	if len(args) == 0 {
+		device, adapter, err := deviceAndAdapter(b, cmd)
+		if err != nil {
+			return nil, "", err
+		}
+		return []string{device}, adapter, nil
+	}
+	adapter, _ = cmd.Flags().GetString("adapter")
+	if adapter == "" {
+		return nil, "", usageError("--adapter is required")
+	}
+	// The adapter is taken from object paths unless --adapter is given,
+	// so a path under another adapter isn't used with the wrong one.
+	pathAdapter := ""
+	for _, arg := range args {
+		if a, address, ok := objectPathAddress(arg); ok {
+			switch {
+			case a == adapter:
+			case cmd.Flags().Changed("adapter"):
+				return nil, "", usageError("%q is on adapter %q, not --adapter=%s", arg, a, adapter)
+			case pathAdapter != "":
+				return nil, "", usageError("%q is on adapter %q, not %q like the other devices", arg, a, pathAdapter)
+			default:
+				debug("using adapter %q from %q", a, arg)
+				adapter = a
+			}
+			pathAdapter = a
+			if !containsString(devices, address) {
+				devices = append(devices, address)
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, inventory.TagPrefix) {
+			tag := strings.TrimPrefix(arg, inventory.TagPrefix)
+			entries := inv.Tagged(tag)
+			if len(entries) == 0 {
+				return nil, "", withExitCode(exitNotFound, errors.Errorf("no devices in the inventory are tagged %q", tag))
+			}
+			for _, e := range entries {
+				if !containsString(devices, e.Address) {
+					devices = append(devices, e.Address)
 				}
-				device = b.Devices[i-1].Address
-				break
 			}
+			continue
+		}
+		device, err := findDevice(b, arg)
+		if err != nil {
+			return nil, "", err
+		}
+		if !containsString(devices, device) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, adapter, nil
+}
+
+// deviceArgs returns the args after the first n, for commands that take
+// their own args before an optional device.
+func deviceArgs(args []string, n int) []string {
+	if len(args) <= n {
+		return nil
+	}
+	return args[n:]
+}
+
+// deviceArgAndAdapter returns the device given as the only arg, or the
+// device chosen the same as deviceAndAdapter when there is no arg.
+func deviceArgAndAdapter(b *bluez.Bluez, cmd *cobra.Command, args []string) (device string, adapter string, err error) {
+	devices, adapter, err := devicesAndAdapter(b, cmd, args)
+	if err != nil {
+		return "", "", err
+	}
+	if len(devices) != 1 {
+		return "", "", withExitCode(exitAmbiguous, errors.Errorf("%q is %d devices, only one device can be given", args[0], len(devices)))
+	}
+	return devices[0], adapter, nil
+}
+
+// findDevice returns the address of the device matching an inventory
+// nickname, a MAC address or a fuzzy name, the name must only match one
+// device.
+func findDevice(b *bluez.Bluez, arg string) (string, error) {
+	if e, ok := inv.Find(arg); ok {
+		return e.Address, nil
+	}
+	if isMacAddress(arg) {
+		return strings.ToUpper(arg), nil
+	}
+	matches := matchDeviceName(b, arg)
+	switch len(matches) {
+	case 0:
+		return "", withExitCode(exitNotFound, errors.Errorf("no bluetooth device found matching %q", arg))
+	case 1:
+		return matches[0].Address, nil
+	}
+	return "", withExitCode(exitAmbiguous, errors.Errorf("%d devices match %q, please use a MAC address or a more specific name", len(matches), arg))
+}
 
+// matchDeviceName fuzzy matches name against the alias of every known
+// device before trying their names, so devices sharing a name can be told
+// apart by giving them an alias. Devices whose alias or name is exactly
+// name are preferred over fuzzy matches.
+func matchDeviceName(b *bluez.Bluez, name string) []bluez.Device {
+	matches := []bluez.Device{}
+	for _, d := range b.Devices {
+		if strings.EqualFold(name, d.Alias) || strings.EqualFold(name, d.Name) {
+			debug("device matches %q exactly, candidate %q", name, d.Address)
+			matches = append(matches, d)
 		}
 	}
-	return device, adapter, nil
+	if len(matches) > 0 {
+		return matches
+	}
+	for _, d := range b.Devices {
+		if similar(name, d.Alias) {
+			debug("device alias matches %q, candidate %q", d.Alias, d.Address)
+			matches = append(matches, d)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+	for _, d := range b.Devices {
+		if similar(name, d.Name) {
+			debug("device name matches %q, candidate %q", d.Name, d.Address)
+			matches = append(matches, d)
+		}
+	}
+	return matches
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// objectPathAddress returns the adapter and the address of a device from
+// its bluez object path, ie: "/org/bluez/hci0/dev_2C_41_A1_49_37_CF".
+func objectPathAddress(s string) (adapter, address string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(s, "/org/bluez/"), "/")
+	// A child object of the device, ie: a media endpoint, has more parts.
+	if !strings.HasPrefix(s, "/org/bluez/") || len(parts) < 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "dev_") {
+		return "", "", false
+	}
+	address = strings.Replace(strings.TrimPrefix(parts[1], "dev_"), "_", ":", -1)
+	if !isMacAddress(address) {
+		return "", "", false
+	}
+	return parts[0], strings.ToUpper(address), true
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
 
+func isMacAddress(s string) bool {
+	_, err := net.ParseMAC(s)
+	return err == nil && len(s) == 17
 }
 
 func similar(match, similarTo string) bool {
@@ -113,6 +336,6 @@ func similar(match, similarTo string) bool {
 
 func debug(message string, args ...interface{}) {
 	if debugging {
-		fmt.Printf("[sluez] %s\n", fmt.Sprintf(message, args...))
+		fmt.Fprintf(os.Stderr, "[sluez] %s\n", fmt.Sprintf(message, args...))
 	}
 }
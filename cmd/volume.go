@@ -0,0 +1,178 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// volumeCmd represents the volume command
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Get or set the absolute volume of a connected audio device",
+}
+
+var volumeGetCmd = &cobra.Command{
+	Use:   "get [DEVICE]",
+	Short: "Print the current volume of a connected audio device",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		_, device, transport, err := deviceTransport(cmd, args)
+		if err != nil {
+			return err
+		}
+		r := newVolumeResult(device, transport.Volume)
+		r.State = transport.State
+		return p.print(r, func() {
+			fmt.Printf("volume=%d max=%d percent=%d state=%s\n", r.Volume, r.Max, r.Percent, r.State)
+		})
+	},
+}
+
+var volumeSetCmd = &cobra.Command{
+	Use:   "set VOLUME [DEVICE]",
+	Short: "Set the volume of a connected audio device, either 0-127 or a percentage like '50%'",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := parseVolume(args[0])
+		if err != nil {
+			return err
+		}
+		return changeVolume(cmd, deviceArgs(args, 1), func(uint16) uint16 { return volume })
+	},
+}
+
+var volumeUpCmd = &cobra.Command{
+	Use:   "up [DEVICE]",
+	Short: "Increase the volume of a connected audio device",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		step, _ := cmd.Flags().GetUint16("step")
+		return changeVolume(cmd, args, func(current uint16) uint16 {
+			if current >= bluez.MaxVolume || step > bluez.MaxVolume-current {
+				return bluez.MaxVolume
+			}
+			return current + step
+		})
+	},
+}
+
+var volumeDownCmd = &cobra.Command{
+	Use:   "down [DEVICE]",
+	Short: "Decrease the volume of a connected audio device",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		step, _ := cmd.Flags().GetUint16("step")
+		return changeVolume(cmd, args, func(current uint16) uint16 {
+			if step > current {
+				return 0
+			}
+			return current - step
+		})
+	},
+}
+
+// volumeResult is the volume of a device printed by the volume commands.
+type volumeResult struct {
+	Device  string `json:"device"`
+	Volume  uint16 `json:"volume"`
+	Max     uint16 `json:"max"`
+	Percent int    `json:"percent"`
+	State   string `json:"state,omitempty"`
+}
+
+func newVolumeResult(device string, volume uint16) volumeResult {
+	return volumeResult{Device: device, Volume: volume, Max: bluez.MaxVolume, Percent: volumePercent(volume)}
+}
+
+// deviceTransport finds the media transport for the device given in args,
+// or the selected device when args is empty.
+func deviceTransport(cmd *cobra.Command, args []string) (*bluez.Bluez, string, bluez.MediaTransport, error) {
+	b, err := newBluez(cmd)
+	if err != nil {
+		return nil, "", bluez.MediaTransport{}, errors.Wrap(err, "unable to get bluez client")
+	}
+	device, adapter, err := deviceArgAndAdapter(b, cmd, args)
+	if err != nil {
+		return nil, "", bluez.MediaTransport{}, errors.Wrap(err, "unable to determine device and/or adapter")
+	}
+	transport, err := b.DeviceTransport(adapter, device)
+	if err != nil {
+		return nil, "", bluez.MediaTransport{}, withExitCode(exitNotFound, errors.Wrap(err, "unable to find audio transport"))
+	}
+	if !transport.HasVolume {
+		return nil, "", bluez.MediaTransport{}, errors.Errorf("device %q does not support absolute volume", device)
+	}
+	return b, device, transport, nil
+}
+
+func changeVolume(cmd *cobra.Command, args []string, volumeFn func(current uint16) uint16) error {
+	p, err := newPrinter(cmd)
+	if err != nil {
+		return err
+	}
+	b, device, transport, err := deviceTransport(cmd, args)
+	if err != nil {
+		return err
+	}
+	volume := volumeFn(transport.Volume)
+	debug("setting volume on transport=%s from %d to %d", transport.Path, transport.Volume, volume)
+	if err := b.SetTransportVolume(transport.Path, volume); err != nil {
+		return errors.Wrap(err, "unable to set volume")
+	}
+	r := newVolumeResult(device, volume)
+	return p.print(r, func() {
+		fmt.Printf("volume=%d max=%d percent=%d\n", r.Volume, r.Max, r.Percent)
+	})
+}
+
+// parseVolume parses either an absolute volume or a percentage of the
+// maximum volume.
+func parseVolume(value string) (uint16, error) {
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseUint(strings.TrimSuffix(value, "%"), 10, 16)
+		if err != nil || percent > 100 {
+			return 0, usageError("%q is an invalid percentage, expected 0%% to 100%%", value)
+		}
+		return uint16((percent*bluez.MaxVolume + 50) / 100), nil
+	}
+	volume, err := strconv.ParseUint(value, 10, 16)
+	if err != nil || volume > bluez.MaxVolume {
+		return 0, usageError("%q is an invalid volume, expected 0 to %d", value, bluez.MaxVolume)
+	}
+	return uint16(volume), nil
+}
+
+func volumePercent(volume uint16) int {
+	return (int(volume)*100 + bluez.MaxVolume/2) / bluez.MaxVolume
+}
+
+func init() {
+	volumeUpCmd.Flags().Uint16("step", 8, "Amount to increase the volume by")
+	volumeDownCmd.Flags().Uint16("step", 8, "Amount to decrease the volume by")
+	volumeCmd.AddCommand(volumeGetCmd, volumeSetCmd, volumeUpCmd, volumeDownCmd)
+	rootCmd.AddCommand(volumeCmd)
+}
@@ -0,0 +1,182 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/vishen/sluez/bluez"
+)
+
+// waitCondition is a state checked by the wait command, device and
+// adapter are nil when the object doesn't exist.
+type waitCondition struct {
+	flag      string
+	state     string
+	onAdapter bool
+	met       func(device, adapter bluez.ObjectState) bool
+}
+
+// waitConditions are the boolean flags of the wait command.
+var waitConditions = []waitCondition{
+	{flag: "connected", state: "connected", met: func(d, a bluez.ObjectState) bool { return d.Bool("org.bluez.Device1", "Connected") }},
+	{flag: "disconnected", state: "disconnected", met: func(d, a bluez.ObjectState) bool { return !d.Bool("org.bluez.Device1", "Connected") }},
+	{flag: "paired", state: "paired", met: func(d, a bluez.ObjectState) bool { return d.Bool("org.bluez.Device1", "Paired") }},
+	{flag: "services-resolved", state: "done resolving services", met: func(d, a bluez.ObjectState) bool { return d.Bool("org.bluez.Device1", "ServicesResolved") }},
+	{flag: "appears", state: "known to bluez", met: func(d, a bluez.ObjectState) bool { return d.Has("org.bluez.Device1") }},
+	{flag: "disappears", state: "removed from bluez", met: func(d, a bluez.ObjectState) bool { return !d.Has("org.bluez.Device1") }},
+	{flag: "powered", state: "powered", onAdapter: true, met: func(d, a bluez.ObjectState) bool { return a.Bool("org.bluez.Adapter1", "Powered") }},
+}
+
+// conflictingWaitFlags can never be satisfied at the same time.
+var conflictingWaitFlags = [][2]string{
+	{"connected", "disconnected"},
+	{"appears", "disappears"},
+	{"connected", "disappears"},
+	{"paired", "disappears"},
+	{"services-resolved", "disappears"},
+	{"battery-below", "disappears"},
+}
+
+// waitResult is printed once the conditions are met.
+type waitResult struct {
+	Device     string   `json:"device,omitempty"`
+	Adapter    string   `json:"adapter"`
+	Conditions []string `json:"conditions"`
+}
+
+// waitCmd represents the wait command
+var waitCmd = &cobra.Command{
+	Use:   "wait [DEVICE]",
+	Short: "Wait for a device or adapter to reach a state",
+	Long: `Wait blocks until every given condition is met, ie: a headset being
+connected or the adapter being powered. The current state is checked first,
+so wait returns straight away if the conditions are already met.
+
+Devices bluez doesn't know about yet, as with --appears, must be given by
+MAC address. Wait exits with 9 if --timeout passes first.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conditions := []waitCondition{}
+		names := []string{}
+		states := []string{}
+		needsDevice := false
+		for _, c := range waitConditions {
+			if on, _ := cmd.Flags().GetBool(c.flag); on {
+				conditions = append(conditions, c)
+				names = append(names, c.flag)
+				states = append(states, c.state)
+				needsDevice = needsDevice || !c.onAdapter
+			}
+		}
+		if cmd.Flags().Changed("battery-below") {
+			below, _ := cmd.Flags().GetUint8("battery-below")
+			if below == 0 || below > 100 {
+				return usageError("--battery-below must be between 1 and 100")
+			}
+			conditions = append(conditions, waitCondition{flag: "battery-below", met: func(d, a bluez.ObjectState) bool {
+				percentage, ok := d.Byte("org.bluez.Battery1", "Percentage")
+				return ok && percentage < below
+			}})
+			names = append(names, "battery-below")
+			states = append(states, fmt.Sprintf("below %d%% battery", below))
+			needsDevice = true
+		}
+		if len(conditions) == 0 {
+			return usageError("no condition given, expected at least one of --connected, --disconnected, --paired, --services-resolved, --battery-below, --appears, --disappears or --powered")
+		}
+		for _, pair := range conflictingWaitFlags {
+			if cmd.Flags().Changed(pair[0]) && cmd.Flags().Changed(pair[1]) {
+				return usageError("--%s and --%s can't be used together", pair[0], pair[1])
+			}
+		}
+		if len(args) > 0 && !needsDevice {
+			return usageError("a device was given but no device condition")
+		}
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		b, err := newBluez(cmd)
+		if err != nil {
+			return errors.Wrap(err, "unable to get bluez client")
+		}
+		adapter, _ := cmd.Flags().GetString("adapter")
+		if adapter == "" {
+			return usageError("--adapter is required")
+		}
+		device := ""
+		if needsDevice {
+			// The adapter can come from an object path given as the device.
+			device, adapter, err = deviceArgAndAdapter(b, cmd, args)
+			if err != nil {
+				return errors.Wrap(err, "unable to determine device and/or adapter")
+			}
+		}
+		if _, ok := b.FindAdapter(adapter); !ok {
+			return withExitCode(exitNotFound, errors.Errorf("no adapter %q found", adapter))
+		}
+
+		adapterPath := dbus.ObjectPath("/org/bluez/" + adapter)
+		devicePath := b.DevicePath(adapter, device)
+		waiting := "adapter " + adapter
+		if device != "" {
+			waiting = "device " + device
+		}
+		waiting = fmt.Sprintf("%s to be %s", waiting, strings.Join(states, " and "))
+		debug("waiting for %s", waiting)
+		err = b.WaitFor(waiting, timeout, func(objects map[dbus.ObjectPath]bluez.ObjectState) bool {
+			for _, c := range conditions {
+				if !c.met(objects[devicePath], objects[adapterPath]) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		r := waitResult{Device: device, Adapter: adapter, Conditions: names}
+		return p.print(r, func() {
+			target := adapter
+			if device != "" {
+				target = device
+			}
+			fmt.Printf("%q is %s\n", target, strings.Join(states, " and "))
+		})
+	},
+}
+
+func init() {
+	for _, c := range waitConditions {
+		target := "the device"
+		if c.onAdapter {
+			target = "the adapter"
+		}
+		waitCmd.Flags().Bool(c.flag, false, fmt.Sprintf("Wait for %s to be %s", target, c.state))
+	}
+	waitCmd.Flags().Uint8("battery-below", 0, "Wait for the device battery percentage to be below N")
+	waitCmd.Flags().Duration("timeout", 0, "How long to wait before giving up, 0 waits forever")
+	rootCmd.AddCommand(waitCmd)
+}
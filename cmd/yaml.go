@@ -0,0 +1,181 @@
+// Copyright © 2018 Jonathan Pentecost <pentecostjonathan@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// yamlMap is a JSON object with its key order kept.
+type yamlMap []yamlEntry
+
+type yamlEntry struct {
+	key   string
+	value interface{}
+}
+
+// writeYAML writes v as a YAML document. v is encoded as JSON first so the
+// json struct tags apply, and fields keep their struct order.
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	emitYAML(&buf, node, 0, "")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func decodeYAMLNode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := yamlMap{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, yamlEntry{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		l := []interface{}{}
+		for dec.More() {
+			value, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, value)
+		}
+		_, err := dec.Token()
+		return l, err
+	}
+	return tok, nil
+}
+
+// emitYAML writes v in block style. prefix is written before the first line
+// instead of the indent, which is how list items get their "- ".
+func emitYAML(w *bytes.Buffer, v interface{}, indent int, prefix string) {
+	spaces := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case yamlMap:
+		if len(v) == 0 {
+			w.WriteString(prefix + "{}\n")
+			return
+		}
+		for i, e := range v {
+			p := spaces
+			if i == 0 {
+				p = prefix
+			}
+			w.WriteString(p + yamlScalar(e.key) + ":")
+			if isYAMLBlock(e.value) {
+				w.WriteString("\n")
+				emitYAML(w, e.value, indent+2, spaces+"  ")
+				continue
+			}
+			w.WriteString(" ")
+			emitYAML(w, e.value, indent+2, "")
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString(prefix + "[]\n")
+			return
+		}
+		for i, item := range v {
+			p := spaces
+			if i == 0 {
+				p = prefix
+			}
+			emitYAML(w, item, indent+2, p+"- ")
+		}
+	default:
+		w.WriteString(prefix + yamlScalar(v) + "\n")
+	}
+}
+
+// isYAMLBlock reports whether v is written over multiple lines.
+func isYAMLBlock(v interface{}) bool {
+	switch v := v.(type) {
+	case yamlMap:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlNeedsQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return ""
+}
+
+// yamlNeedsQuotes reports whether a string would be read back as something
+// other than the same string if it weren't quoted.
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	// Any colon is quoted as YAML 1.1 reads MAC addresses made of digits,
+	// ie: 11:22:33:44:55:66, as base 60 numbers.
+	return strings.Contains(s, ":") || strings.Contains(s, " #")
+}
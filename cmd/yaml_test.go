@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestYAMLScalarQuoting(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"hci0", "hci0"},
+		{"Bose QC35 II", "Bose QC35 II"},
+		{"a#b", "a#b"},
+		{"", `""`},
+		{" padded", `" padded"`},
+		{"trailing ", `"trailing "`},
+		{"2C:41:A1:49:37:CF", `"2C:41:A1:49:37:CF"`},
+		{"11:22:33:44:55:66", `"11:22:33:44:55:66"`},
+		{"key: value", `"key: value"`},
+		{"note #1", `"note #1"`},
+		{"#comment", `"#comment"`},
+		{"-", `"-"`},
+		{"-leading", `"-leading"`},
+		{"- item", `"- item"`},
+		{"true", `"true"`},
+		{"False", `"False"`},
+		{"yes", `"yes"`},
+		{"off", `"off"`},
+		{"null", `"null"`},
+		{"Null", `"Null"`},
+		{"~", `"~"`},
+		{"42", `"42"`},
+		{"-1.5", `"-1.5"`},
+		{"1e3", `"1e3"`},
+		{"*alias", `"*alias"`},
+		{"&anchor", `"&anchor"`},
+		{"!tag", `"!tag"`},
+		{"[list]", `"[list]"`},
+		{"{map}", `"{map}"`},
+		{"'single'", `"'single'"`},
+		{`"double"`, `"\"double\""`},
+		{"line one\nline two", `"line one\nline two"`},
+		{"tab\there", `"tab\there"`},
+	}
+	for _, tt := range tests {
+		if got := yamlScalar(tt.in); got != tt.want {
+			t.Errorf("yamlScalar(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestYAMLScalarTypes(t *testing.T) {
+	var buf bytes.Buffer
+	v := struct {
+		Bool   bool        `json:"bool"`
+		Int    int         `json:"int"`
+		Float  float64     `json:"float"`
+		Null   interface{} `json:"null"`
+		Nested *struct{}   `json:"nested"`
+	}{Bool: true, Int: -3, Float: 1.5}
+	if err := writeYAML(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	want := "bool: true\nint: -3\nfloat: 1.5\n\"null\": null\nnested: null\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	type transport struct {
+		Path  string `json:"path"`
+		Codec string `json:"codec,omitempty"`
+	}
+	type device struct {
+		Address    string            `json:"address"`
+		Connected  bool              `json:"connected"`
+		Tags       []string          `json:"tags,omitempty"`
+		Notes      string            `json:"notes,omitempty"`
+		Transports []transport       `json:"transports"`
+		Properties map[string]string `json:"properties"`
+	}
+	tests := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{
+			name: "omitempty fields are left out",
+			in:   device{Address: "2C:41:A1:49:37:CF", Transports: []transport{}, Properties: map[string]string{}},
+			want: `address: "2C:41:A1:49:37:CF"
+connected: false
+transports: []
+properties: {}
+`,
+		},
+		{
+			name: "nested slices and maps",
+			in: device{
+				Address:    "2C:41:A1:49:37:CF",
+				Connected:  true,
+				Tags:       []string{"room=office", "true"},
+				Notes:      "JBL on the desk\nleft speaker",
+				Transports: []transport{{Path: "/org/bluez/hci0/dev_2C_41_A1_49_37_CF/fd0", Codec: "SBC"}, {Path: "/fd1"}},
+				Properties: map[string]string{"b": "2", "a": "x"},
+			},
+			want: `address: "2C:41:A1:49:37:CF"
+connected: true
+tags:
+  - room=office
+  - "true"
+notes: "JBL on the desk\nleft speaker"
+transports:
+  - path: /org/bluez/hci0/dev_2C_41_A1_49_37_CF/fd0
+    codec: SBC
+  - path: /fd1
+properties:
+  a: x
+  b: "2"
+`,
+		},
+		{
+			name: "top level slice",
+			in:   []transport{{Path: "/a"}, {Path: "/b", Codec: "AAC"}},
+			want: `- path: /a
+- path: /b
+  codec: AAC
+`,
+		},
+		{
+			name: "nested lists",
+			in:   [][]int{{1, 2}, {}, {3}},
+			want: `- - 1
+  - 2
+- []
+- - 3
+`,
+		},
+		{
+			name: "empty top level slice",
+			in:   []string{},
+			want: "[]\n",
+		},
+		{
+			name: "empty top level map",
+			in:   struct{}{},
+			want: "{}\n",
+		},
+		{
+			name: "maps in maps",
+			in:   map[string]interface{}{"outer": map[string]interface{}{"inner": map[string]int{"n": 1}, "empty": []int{}}},
+			want: `outer:
+  empty: []
+  inner:
+    "n": 1
+`,
+		},
+		{
+			name: "keys needing quotes",
+			in:   map[string]int{"2C:41:A1:49:37:CF": 1, "null": 2},
+			want: `"2C:41:A1:49:37:CF": 1
+"null": 2
+`,
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeYAML(&buf, tt.in); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package config reads and writes the sluez configuration file, which
+// holds defaults for flags and settings for particular devices. The file
+// has a "key = value" setting per line, blank lines and lines starting with
+// "#" are ignored.
+//
+// Settings are also read from SLUEZ_* environment variables, ie:
+// SLUEZ_RETRY_DELAY for "retry-delay", which take precedence over the file.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources of a setting.
+const (
+	SourceFile = "file"
+	SourceEnv  = "env"
+)
+
+// Key is a setting that can be configured.
+type Key struct {
+	Name  string
+	Usage string
+	// Env is the environment variable overriding the setting, "" if it
+	// can't be set from the environment.
+	Env      string
+	validate func(string) error
+}
+
+// devicePrefix starts the keys of settings for a single device, ie:
+// "device.2C:41:A1:49:37:CF.audio-profile".
+const devicePrefix = "device."
+
+// Keys are the global settings.
+var Keys = []Key{
+	{Name: "adapter", Usage: "Adapter used when --adapter isn't given, ie: hci1"},
+	{Name: "favorites", Usage: "Comma separated devices, by address or name, used in order when no device is given", validate: validateList},
+	{Name: "output", Usage: "Output format used when --output isn't given: text, json, ndjson or yaml", validate: validateOutput},
+	{Name: "audio-profile", Usage: "Audio profile selected once an audio device connects, ie: a2dp or headset"},
+	{Name: "audio-switch", Usage: "Whether to move audio output to an audio device once it connects, true or false", validate: validateBool},
+	{Name: "retries", Usage: "How many times connect and auto retry a device that doesn't respond", validate: validateRetries},
+	{Name: "retry-delay", Usage: "Delay before the first retry, ie: 2s", validate: validateDuration},
+}
+
+// DeviceKeys are the settings that can be set for a single device, they
+// take precedence over the global setting of the same name.
+var DeviceKeys = []Key{
+	{Name: "audio-profile", Usage: "Audio profile selected once the device connects"},
+	{Name: "audio-switch", Usage: "Whether to move audio output to the device once it connects", validate: validateBool},
+}
+
+func init() {
+	for i, k := range Keys {
+		Keys[i].Env = "SLUEZ_" + strings.ToUpper(strings.Replace(k.Name, "-", "_", -1))
+	}
+}
+
+// EnvPath is the environment variable overriding the path of the file.
+const EnvPath = "SLUEZ_CONFIG"
+
+// DefaultPath returns the path of the file, $XDG_CONFIG_HOME/sluez/config
+// unless SLUEZ_CONFIG is set.
+func DefaultPath() string {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path
+	}
+	return filepath.Join(Dir(), "config")
+}
+
+// Dir returns the directory sluez keeps its files in,
+// $XDG_CONFIG_HOME/sluez or ~/.config/sluez.
+func Dir() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configDir, "sluez")
+}
+
+// WriteFile writes data to path, replacing the file atomically so a failed
+// write doesn't lose what was there before. The directory is created if
+// needed.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Config is the configuration read from a file and the environment.
+type Config struct {
+	Path string
+
+	// Warnings are the settings that were ignored because they are
+	// unknown or invalid.
+	Warnings []string
+
+	file map[string]string
+	env  map[string]string
+	// ignored are the lines of the file that were ignored, they are kept
+	// when the file is written so a typo doesn't lose a setting.
+	ignored []ignoredLine
+}
+
+// ignoredLine is a line of the file that wasn't used, key is "" if the line
+// isn't a "key = value" setting.
+type ignoredLine struct {
+	key  string
+	text string
+}
+
+// Load reads the file at path, a missing file is an empty configuration.
+// Settings from the environment are read as well. Unknown or invalid
+// settings are ignored and added to Warnings, only a file that can't be
+// read is an error.
+func Load(path string) (*Config, error) {
+	c := &Config{Path: path, file: map[string]string{}, env: map[string]string{}}
+	for _, k := range Keys {
+		if v, ok := os.LookupEnv(k.Env); ok {
+			if err := k.check(v); err != nil {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("ignoring invalid %s: %v", k.Env, err))
+				continue
+			}
+			c.env[k.Name] = v
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.Index(text, "=")
+		if i < 0 {
+			c.ignore(fmt.Sprintf("%s:%d: expected \"key = value\"", path, line), "", text)
+			continue
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		k, err := lookup(key)
+		if err != nil {
+			c.ignore(fmt.Sprintf("%s:%d: %v", path, line, err), key, text)
+			continue
+		}
+		if err := k.check(value); err != nil {
+			c.ignore(fmt.Sprintf("%s:%d: invalid %s: %v", path, line, key, err), normaliseKey(key), text)
+			continue
+		}
+		c.file[normaliseKey(key)] = value
+	}
+	return c, nil
+}
+
+func (c *Config) ignore(warning, key, text string) {
+	c.Warnings = append(c.Warnings, "ignoring "+warning)
+	c.ignored = append(c.ignored, ignoredLine{key: key, text: text})
+}
+
+// Get returns the value of a setting and where it came from, the
+// environment takes precedence over the file. False is returned if the
+// setting isn't set.
+func (c *Config) Get(key string) (value, source string, ok bool) {
+	key = normaliseKey(key)
+	if v, ok := c.env[key]; ok {
+		return v, SourceEnv, true
+	}
+	if v, ok := c.file[key]; ok {
+		return v, SourceFile, true
+	}
+	return "", "", false
+}
+
+// Device returns the value of a setting for a device, falling back to the
+// global setting. The global setting from the environment takes precedence
+// over the device setting from the file.
+func (c *Config) Device(address, key string) (value, source string, ok bool) {
+	if v, ok := c.env[key]; ok {
+		return v, SourceEnv, true
+	}
+	if v, ok := c.file[normaliseKey(devicePrefix+address+"."+key)]; ok {
+		return v, SourceFile, true
+	}
+	return c.Get(key)
+}
+
+// Favorites returns the favorite devices in order.
+func (c *Config) Favorites() []string {
+	v, _, _ := c.Get("favorites")
+	return splitList(v)
+}
+
+// Setting is a setting and where it came from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// List returns every setting that is set, sorted by key.
+func (c *Config) List() []Setting {
+	settings := []Setting{}
+	for key := range c.file {
+		if _, ok := c.env[key]; !ok {
+			settings = append(settings, Setting{Key: key, Value: c.file[key], Source: SourceFile})
+		}
+	}
+	for key, value := range c.env {
+		settings = append(settings, Setting{Key: key, Value: value, Source: SourceEnv})
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings
+}
+
+// Set changes a setting in the file and writes it, an empty value removes
+// the setting. Settings from the environment aren't written.
+func (c *Config) Set(key, value string) error {
+	k, err := lookup(key)
+	if err != nil {
+		return err
+	}
+	key = normaliseKey(key)
+	if value == "" {
+		delete(c.file, key)
+	} else {
+		if err := k.check(value); err != nil {
+			return fmt.Errorf("invalid %s: %v", key, err)
+		}
+		c.file[key] = value
+	}
+	// An ignored line for the setting is replaced by the new value.
+	ignored := c.ignored[:0]
+	for _, l := range c.ignored {
+		if l.key != key {
+			ignored = append(ignored, l)
+		}
+	}
+	c.ignored = ignored
+	return c.save()
+}
+
+// save writes the file.
+func (c *Config) save() error {
+	keys := []string{}
+	for key := range c.file {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("# sluez configuration, see 'sluez config --help'.\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", key, c.file[key])
+	}
+	for _, l := range c.ignored {
+		fmt.Fprintf(&b, "%s\n", l.text)
+	}
+	return WriteFile(c.Path, []byte(b.String()))
+}
+
+// lookup returns the key for a global or device setting.
+func lookup(key string) (Key, error) {
+	if strings.HasPrefix(key, devicePrefix) {
+		rest := strings.TrimPrefix(key, devicePrefix)
+		i := strings.LastIndex(rest, ".")
+		if i <= 0 {
+			return Key{}, fmt.Errorf("%q is an invalid key, expected device.ADDRESS.SETTING", key)
+		}
+		for _, k := range DeviceKeys {
+			if k.Name == rest[i+1:] {
+				return k, nil
+			}
+		}
+		return Key{}, fmt.Errorf("%q is an unknown device setting", rest[i+1:])
+	}
+	for _, k := range Keys {
+		if k.Name == key {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%q is an unknown setting", key)
+}
+
+// normaliseKey upper cases the address in device keys, so they match
+// however the address was written.
+func normaliseKey(key string) string {
+	if !strings.HasPrefix(key, devicePrefix) {
+		return key
+	}
+	rest := strings.TrimPrefix(key, devicePrefix)
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return key
+	}
+	return devicePrefix + strings.ToUpper(rest[:i]) + rest[i:]
+}
+
+func (k Key) check(value string) error {
+	if k.validate == nil {
+		return nil
+	}
+	return k.validate(value)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func validateList(value string) error {
+	if len(splitList(value)) == 0 {
+		return fmt.Errorf("%q has no items", value)
+	}
+	return nil
+}
+
+func validateOutput(value string) error {
+	switch value {
+	case "text", "json", "ndjson", "yaml":
+		return nil
+	}
+	return fmt.Errorf("%q is an invalid output format, expected text, json, ndjson or yaml", value)
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("%q is invalid, expected true or false", value)
+	}
+	return nil
+}
+
+func validateRetries(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 0 {
+		return fmt.Errorf("%q is invalid, expected a number of retries", value)
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("%q is an invalid duration, ie: 2s", value)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package inventory keeps local nicknames, tags and notes for bluetooth
+// devices. Unlike the alias of a device these are never sent to bluez, so
+// they can be shared between machines or kept per user.
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vishen/sluez/config"
+)
+
+// EnvPath is the environment variable overriding the path of the file.
+const EnvPath = "SLUEZ_INVENTORY"
+
+// DefaultPath returns the path of the inventory file,
+// $XDG_CONFIG_HOME/sluez/inventory.json unless SLUEZ_INVENTORY is set.
+func DefaultPath() string {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path
+	}
+	return filepath.Join(config.Dir(), "inventory.json")
+}
+
+// Entry is a device in the inventory.
+type Entry struct {
+	Nickname string   `json:"nickname"`
+	Address  string   `json:"address"`
+	Tags     []string `json:"tags,omitempty"`
+	Notes    string   `json:"notes,omitempty"`
+}
+
+// HasTag reports whether the entry is tagged with tag, ignoring case.
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Inventory is the set of devices in the inventory file, sorted by
+// nickname.
+type Inventory struct {
+	Path    string
+	Entries []Entry
+}
+
+// Load reads the inventory at path, a missing file is an empty inventory.
+func Load(path string) (*Inventory, error) {
+	inv := &Inventory{Path: path, Entries: []Entry{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &inv.Entries); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return inv, nil
+}
+
+// Save writes the inventory, replacing the file atomically.
+func (inv *Inventory) Save() error {
+	sort.Slice(inv.Entries, func(i, j int) bool {
+		return inv.Entries[i].Nickname < inv.Entries[j].Nickname
+	})
+	data, err := json.MarshalIndent(inv.Entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(inv.Path, append(data, '\n'))
+}
+
+// Find returns the entry with the nickname, ignoring case.
+func (inv *Inventory) Find(nickname string) (Entry, bool) {
+	for _, e := range inv.Entries {
+		if strings.EqualFold(e.Nickname, nickname) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// ByAddress returns the entry for a device address.
+func (inv *Inventory) ByAddress(address string) (Entry, bool) {
+	for _, e := range inv.Entries {
+		if strings.EqualFold(e.Address, address) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Tagged returns every entry tagged with tag.
+func (inv *Inventory) Tagged(tag string) []Entry {
+	entries := []Entry{}
+	for _, e := range inv.Entries {
+		if e.HasTag(tag) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Add adds an entry, or updates the entry of the same device keeping its
+// tags. Nicknames must be unique.
+func (inv *Inventory) Add(entry Entry) error {
+	if err := ValidNickname(entry.Nickname); err != nil {
+		return err
+	}
+	entry.Address = strings.ToUpper(entry.Address)
+	if e, ok := inv.Find(entry.Nickname); ok && !strings.EqualFold(e.Address, entry.Address) {
+		return fmt.Errorf("nickname %q is already used for %s", e.Nickname, e.Address)
+	}
+	for i, e := range inv.Entries {
+		if strings.EqualFold(e.Address, entry.Address) {
+			inv.Entries[i].Nickname = entry.Nickname
+			inv.Entries[i].Tags = mergeTags(e.Tags, entry.Tags)
+			if entry.Notes != "" {
+				inv.Entries[i].Notes = entry.Notes
+			}
+			return nil
+		}
+	}
+	entry.Tags = mergeTags(nil, entry.Tags)
+	inv.Entries = append(inv.Entries, entry)
+	return nil
+}
+
+// Remove removes the entry with the nickname, false is returned if there
+// is none.
+func (inv *Inventory) Remove(nickname string) (Entry, bool) {
+	for i, e := range inv.Entries {
+		if strings.EqualFold(e.Nickname, nickname) {
+			inv.Entries = append(inv.Entries[:i], inv.Entries[i+1:]...)
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Tag adds tags to the entry with the nickname, or removes them when
+// remove is set. The updated entry is returned.
+func (inv *Inventory) Tag(nickname string, tags []string, remove bool) (Entry, error) {
+	for i, e := range inv.Entries {
+		if !strings.EqualFold(e.Nickname, nickname) {
+			continue
+		}
+		if !remove {
+			inv.Entries[i].Tags = mergeTags(e.Tags, tags)
+			return inv.Entries[i], nil
+		}
+		kept := []string{}
+		for _, t := range e.Tags {
+			if !(Entry{Tags: tags}).HasTag(t) {
+				kept = append(kept, t)
+			}
+		}
+		inv.Entries[i].Tags = kept
+		return inv.Entries[i], nil
+	}
+	return Entry{}, fmt.Errorf("no device with nickname %q", nickname)
+}
+
+// ValidNickname returns an error if a nickname can't be told apart from
+// an address or a tag.
+func ValidNickname(nickname string) error {
+	switch {
+	case nickname == "":
+		return fmt.Errorf("nickname is empty")
+	case strings.ContainsAny(nickname, " \t\n,"):
+		return fmt.Errorf("nickname %q can't contain spaces or commas", nickname)
+	case strings.HasPrefix(nickname, TagPrefix):
+		return fmt.Errorf("nickname %q can't start with %q", nickname, TagPrefix)
+	case strings.Count(nickname, ":") == 5:
+		return fmt.Errorf("nickname %q looks like an address", nickname)
+	}
+	return nil
+}
+
+// TagPrefix selects every device with a tag when used in place of a
+// device, ie: "tag:office".
+const TagPrefix = "tag:"
+
+// mergeTags adds tags that aren't in existing yet, the result is sorted.
+func mergeTags(existing, tags []string) []string {
+	merged := append([]string{}, existing...)
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t != "" && !(Entry{Tags: merged}).HasTag(t) {
+			merged = append(merged, t)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,166 @@
+package pulseaudio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Card is a sound card known to the server, bluetooth devices each have a
+// card named "bluez_card.<MAC>" with a profile per audio profile.
+type Card struct {
+	Index         uint32
+	Name          string
+	Driver        string
+	Profiles      []Profile
+	ActiveProfile string
+	Properties    map[string]string
+}
+
+// Profile is a card profile, ie: "a2dp_sink" or "headset_head_unit".
+type Profile struct {
+	Name        string
+	Description string
+	Sinks       uint32
+	Sources     uint32
+	Priority    uint32
+	Available   bool
+}
+
+// Profile groups accepted by Card.FindProfile, pulseaudio and pipewire
+// name the same profile differently.
+var profileGroups = map[string][]string{
+	"a2dp":    {"a2dp_sink", "a2dp-sink"},
+	"headset": {"headset_head_unit", "headset-head-unit", "handsfree_head_unit", "handsfree-head-unit"},
+	"off":     {"off"},
+}
+
+// BluetoothCardName returns the card name used for a bluetooth device.
+func BluetoothCardName(deviceMac string) string {
+	return "bluez_card." + strings.Replace(deviceMac, ":", "_", -1)
+}
+
+// FindProfile returns the card profile matching name. The name is either
+// an exact profile name or one of "a2dp", "headset" or "off", in which case
+// the available profile with the highest priority in that group is used.
+func (c Card) FindProfile(name string) (Profile, error) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	prefixes, ok := profileGroups[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("card %q has no profile %q", c.Name, name)
+	}
+	candidates := []Profile{}
+	for _, p := range c.Profiles {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(p.Name, prefix) {
+				candidates = append(candidates, p)
+				break
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Available != candidates[j].Available {
+			return candidates[i].Available
+		}
+		return candidates[i].Priority > candidates[j].Priority
+	})
+	if len(candidates) == 0 {
+		return Profile{}, fmt.Errorf("card %q has no %s profile", c.Name, name)
+	}
+	if !candidates[0].Available {
+		return Profile{}, fmt.Errorf("card %q has no available %s profile", c.Name, name)
+	}
+	return candidates[0], nil
+}
+
+// Cards returns all the cards known to the server.
+func (c *Client) Cards() ([]Card, error) {
+	r, err := c.request(commandGetCardInfoList, &tagWriter{})
+	if err != nil {
+		return nil, err
+	}
+	cards := []Card{}
+	for !r.empty() {
+		card := Card{}
+		card.Index = r.u32()
+		card.Name = r.string()
+		r.u32() // owner module
+		card.Driver = r.string()
+		profiles := r.u32()
+		for i := uint32(0); i < profiles && r.err == nil; i++ {
+			p := Profile{
+				Name:        r.string(),
+				Description: r.string(),
+				Sinks:       r.u32(),
+				Sources:     r.u32(),
+				Priority:    r.u32(),
+				Available:   true,
+			}
+			if c.version >= 29 {
+				p.Available = r.u32() != 0
+			}
+			card.Profiles = append(card.Profiles, p)
+		}
+		card.ActiveProfile = r.string()
+		card.Properties = r.propList()
+		if c.version >= 26 {
+			r.skipCardPorts(c.version)
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// skipCardPorts skips over the ports of a card, they aren't needed for
+// managing profiles.
+func (r *tagReader) skipCardPorts(version uint32) {
+	ports := r.u32()
+	for i := uint32(0); i < ports && r.err == nil; i++ {
+		r.string() // name
+		r.string() // description
+		r.u32()    // priority
+		r.u32()    // available
+		r.u8()     // direction
+		r.propList()
+		profiles := r.u32()
+		for j := uint32(0); j < profiles && r.err == nil; j++ {
+			r.string()
+		}
+		if version >= 27 {
+			r.u64(tagS64) // latency offset
+		}
+	}
+}
+
+// BluetoothCard returns the card for a bluetooth device, the card only
+// exists while the device is connected.
+func (c *Client) BluetoothCard(deviceMac string) (Card, error) {
+	cards, err := c.Cards()
+	if err != nil {
+		return Card{}, err
+	}
+	name := BluetoothCardName(deviceMac)
+	for _, card := range cards {
+		if card.Name == name || strings.EqualFold(card.Properties["device.string"], deviceMac) {
+			return card, nil
+		}
+	}
+	return Card{}, fmt.Errorf("no audio card found for bluetooth device %q", deviceMac)
+}
+
+// SetCardProfile changes the active profile of a card.
+func (c *Client) SetCardProfile(card, profile string) error {
+	w := &tagWriter{}
+	w.u32(invalidIndex)
+	w.stringOrNull(card)
+	w.string(profile)
+	_, err := c.request(commandSetCardProfile, w)
+	return err
+}
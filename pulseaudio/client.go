@@ -0,0 +1,225 @@
+// Package pulseaudio is a minimal client for the pulseaudio native protocol,
+// which is also served by pipewire-pulse. It only implements what is needed
+// to manage the cards and sinks created for bluetooth devices.
+package pulseaudio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// protocolVersion is the highest protocol version this client knows how
+	// to parse, the version used is the lowest of this and the server's.
+	protocolVersion = 32
+
+	// controlChannel is the channel used for command packets, every other
+	// channel carries stream data which is ignored.
+	controlChannel = 0xffffffff
+
+	descriptorSize = 20
+	cookieSize     = 256
+
+	// DefaultTimeout is used for each request when no timeout is set.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Commands sent to and received from the server.
+// https://gitlab.freedesktop.org/pulseaudio/pulseaudio/-/blob/master/src/pulsecore/native-common.h
+const (
+	commandError                   uint32 = 0
+	commandReply                   uint32 = 2
+	commandAuth                    uint32 = 8
+	commandSetClientName           uint32 = 9
+	commandGetServerInfo           uint32 = 20
+	commandGetSinkInfoList         uint32 = 22
+	commandGetSourceInfoList       uint32 = 24
+	commandGetSinkInputInfoList    uint32 = 30
+	commandGetSourceOutputInfoList uint32 = 32
+	commandSetDefaultSink          uint32 = 44
+	commandSetDefaultSource        uint32 = 45
+	commandMoveSinkInput           uint32 = 67
+	commandMoveSourceOutput        uint32 = 68
+	commandGetCardInfoList         uint32 = 89
+	commandSetCardProfile          uint32 = 90
+)
+
+// Client is a connection to a pulseaudio server. Requests are serialised,
+// so a Client is safe to use from multiple goroutines.
+type Client struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	mu      sync.Mutex
+	tag     uint32
+	version uint32
+}
+
+// ServerPath returns the unix socket path of the pulseaudio server for the
+// current user, honouring PULSE_SERVER when it points at a unix socket.
+func ServerPath() string {
+	if server := os.Getenv("PULSE_SERVER"); strings.HasPrefix(server, "unix:") {
+		return strings.TrimPrefix(server, "unix:")
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(runtimeDir, "pulse", "native")
+}
+
+// Dial connects to the pulseaudio server listening on the unix socket at
+// path and authenticates the client.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, DefaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to pulseaudio at %q: %v", path, err)
+	}
+	c, err := NewClient(conn, readCookie())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient authenticates with the server on conn using cookie, which may
+// be nil when the server doesn't require one (as is the case for
+// pipewire-pulse and for same-user unix socket connections).
+func NewClient(conn net.Conn, cookie []byte) (*Client, error) {
+	c := &Client{conn: conn, timeout: DefaultTimeout, version: protocolVersion}
+	if len(cookie) != cookieSize {
+		cookie = make([]byte, cookieSize)
+	}
+
+	w := &tagWriter{}
+	w.u32(protocolVersion)
+	w.arbitrary(cookie)
+	r, err := c.request(commandAuth, w)
+	if err != nil {
+		return nil, fmt.Errorf("unable to authenticate with pulseaudio: %v", err)
+	}
+	// The upper bits of the version are used to negotiate shared memory,
+	// which isn't supported by this client.
+	if serverVersion := r.u32() & 0xffff; serverVersion < c.version {
+		c.version = serverVersion
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	w = &tagWriter{}
+	w.propList(map[string]string{"application.name": "sluez"})
+	if _, err := c.request(commandSetClientName, w); err != nil {
+		return nil, fmt.Errorf("unable to set client name: %v", err)
+	}
+	return c, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SetTimeout sets how long to wait for the server to reply to each request.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.timeout = timeout
+	c.mu.Unlock()
+}
+
+// request sends a command and waits for the reply with the same tag, any
+// other packets received in the meantime are ignored.
+func (c *Client) request(command uint32, args *tagWriter) (*tagReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tag++
+	tag := c.tag
+	w := &tagWriter{}
+	w.u32(command)
+	w.u32(tag)
+	payload := append(w.bytes(), args.bytes()...)
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	descriptor := make([]byte, descriptorSize)
+	binary.BigEndian.PutUint32(descriptor[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(descriptor[4:], controlChannel)
+	if _, err := c.conn.Write(append(descriptor, payload...)); err != nil {
+		return nil, err
+	}
+
+	for {
+		channel, packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		if channel != controlChannel {
+			continue
+		}
+		r := &tagReader{data: packet}
+		replyCommand := r.u32()
+		replyTag := r.u32()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if replyTag != tag {
+			continue
+		}
+		switch replyCommand {
+		case commandReply:
+			return r, nil
+		case commandError:
+			return nil, &Error{Command: command, Code: r.u32()}
+		default:
+			return nil, fmt.Errorf("unexpected reply command %d for command %d", replyCommand, command)
+		}
+	}
+}
+
+func (c *Client) readPacket() (uint32, []byte, error) {
+	descriptor := make([]byte, descriptorSize)
+	if _, err := io.ReadFull(c.conn, descriptor); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(descriptor[0:])
+	channel := binary.BigEndian.Uint32(descriptor[4:])
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, packet); err != nil {
+		return 0, nil, err
+	}
+	return channel, packet, nil
+}
+
+// readCookie reads the authentication cookie from the usual locations, nil
+// is returned if there is no cookie.
+func readCookie() []byte {
+	paths := []string{}
+	if path := os.Getenv("PULSE_COOKIE"); path != "" {
+		paths = append(paths, path)
+	}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	paths = append(paths,
+		filepath.Join(configDir, "pulse", "cookie"),
+		filepath.Join(os.Getenv("HOME"), ".pulse-cookie"),
+	)
+	for _, path := range paths {
+		if cookie, err := ioutil.ReadFile(path); err == nil && len(cookie) == cookieSize {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package pulseaudio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// fakeServer answers requests on one end of a net.Pipe, handle returns the
+// reply payload or a non zero error code for each command.
+type fakeServer struct {
+	t        *testing.T
+	conn     net.Conn
+	handle   func(command uint32, r *tagReader) (*tagWriter, uint32)
+	commands []uint32
+	done     chan struct{}
+}
+
+func newFakeServer(t *testing.T, handle func(command uint32, r *tagReader) (*tagWriter, uint32)) (*fakeServer, net.Conn) {
+	client, server := net.Pipe()
+	s := &fakeServer{t: t, conn: server, handle: handle, done: make(chan struct{})}
+	go s.serve()
+	return s, client
+}
+
+func (s *fakeServer) serve() {
+	defer close(s.done)
+	for {
+		descriptor := make([]byte, descriptorSize)
+		if _, err := io.ReadFull(s.conn, descriptor); err != nil {
+			return
+		}
+		packet := make([]byte, binary.BigEndian.Uint32(descriptor))
+		if _, err := io.ReadFull(s.conn, packet); err != nil {
+			return
+		}
+		r := &tagReader{data: packet}
+		command := r.u32()
+		tag := r.u32()
+		if r.err != nil {
+			s.t.Errorf("unable to read request header: %v", r.err)
+			return
+		}
+		s.commands = append(s.commands, command)
+
+		args, code := s.handle(command, r)
+		w := &tagWriter{}
+		if code != 0 {
+			w.u32(commandError)
+			w.u32(tag)
+			w.u32(code)
+		} else {
+			w.u32(commandReply)
+			w.u32(tag)
+			if args != nil {
+				w.buf.Write(args.bytes())
+			}
+		}
+		reply := make([]byte, descriptorSize)
+		binary.BigEndian.PutUint32(reply[0:], uint32(w.buf.Len()))
+		binary.BigEndian.PutUint32(reply[4:], controlChannel)
+		if _, err := s.conn.Write(append(reply, w.bytes()...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) close() {
+	s.conn.Close()
+	<-s.done
+}
+
+// handshake answers the auth and client name commands sent by NewClient.
+func handshake(t *testing.T, version uint32, next func(command uint32, r *tagReader) (*tagWriter, uint32)) func(command uint32, r *tagReader) (*tagWriter, uint32) {
+	return func(command uint32, r *tagReader) (*tagWriter, uint32) {
+		switch command {
+		case commandAuth:
+			if v := r.u32(); v != protocolVersion {
+				t.Errorf("auth version = %d, want %d", v, protocolVersion)
+			}
+			if cookie := r.arbitrary(); len(cookie) != cookieSize {
+				t.Errorf("auth cookie is %d bytes, want %d", len(cookie), cookieSize)
+			}
+			w := &tagWriter{}
+			w.u32(version)
+			return w, 0
+		case commandSetClientName:
+			if props := r.propList(); props["application.name"] != "sluez" {
+				t.Errorf("client name properties = %v", props)
+			}
+			w := &tagWriter{}
+			w.u32(7) // client index
+			return w, 0
+		}
+		if next == nil {
+			t.Errorf("unexpected command %d", command)
+			return nil, ErrCommand
+		}
+		return next(command, r)
+	}
+}
+
+func TestNewClientHandshake(t *testing.T) {
+	tests := []struct {
+		serverVersion uint32
+		want          uint32
+	}{
+		{serverVersion: 35, want: protocolVersion},
+		{serverVersion: 28, want: 28},
+		// The upper bits negotiate shared memory and are ignored.
+		{serverVersion: 0x80000000 | 30, want: 30},
+	}
+	for _, tt := range tests {
+		s, conn := newFakeServer(t, handshake(t, tt.serverVersion, nil))
+		c, err := NewClient(conn, nil)
+		if err != nil {
+			t.Fatalf("server version %#x: NewClient failed: %v", tt.serverVersion, err)
+		}
+		if c.version != tt.want {
+			t.Errorf("server version %#x: negotiated version %d, want %d", tt.serverVersion, c.version, tt.want)
+		}
+		c.Close()
+		s.close()
+		if want := []uint32{commandAuth, commandSetClientName}; !reflect.DeepEqual(s.commands, want) {
+			t.Errorf("commands = %v, want %v", s.commands, want)
+		}
+	}
+}
+
+func TestNewClientAuthFailure(t *testing.T) {
+	s, conn := newFakeServer(t, func(command uint32, r *tagReader) (*tagWriter, uint32) {
+		return nil, ErrAccess
+	})
+	defer s.close()
+	_, err := NewClient(conn, nil)
+	if err == nil {
+		t.Fatal("NewClient succeeded, want an error")
+	}
+	want := "unable to authenticate with pulseaudio: pulseaudio command 8 failed: access denied"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestTagStructRoundTrip(t *testing.T) {
+	w := &tagWriter{}
+	w.u32(42)
+	w.string("bluez_card.2C_41_A1_49_37_CF")
+	w.stringOrNull("")
+	w.stringOrNull("a2dp_sink")
+	w.arbitrary([]byte{1, 2, 3})
+	w.propList(map[string]string{"device.string": "2C:41:A1:49:37:CF"})
+
+	r := &tagReader{data: w.bytes()}
+	if v := r.u32(); v != 42 {
+		t.Errorf("u32 = %d, want 42", v)
+	}
+	if v := r.string(); v != "bluez_card.2C_41_A1_49_37_CF" {
+		t.Errorf("string = %q", v)
+	}
+	if v := r.string(); v != "" {
+		t.Errorf("null string = %q, want \"\"", v)
+	}
+	if v := r.string(); v != "a2dp_sink" {
+		t.Errorf("stringOrNull = %q, want a2dp_sink", v)
+	}
+	if v := r.arbitrary(); !bytes.Equal(v, []byte{1, 2, 3}) {
+		t.Errorf("arbitrary = %v", v)
+	}
+	if v := r.propList(); !reflect.DeepEqual(v, map[string]string{"device.string": "2C:41:A1:49:37:CF"}) {
+		t.Errorf("propList = %v", v)
+	}
+	if r.err != nil || !r.empty() {
+		t.Errorf("err = %v, %d bytes left", r.err, len(r.data))
+	}
+}
+
+func TestTagReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(r *tagReader)
+	}{
+		{"wrong tag", []byte{tagString, 'a', 0}, func(r *tagReader) { r.u32() }},
+		{"short u32", []byte{tagU32, 0, 0}, func(r *tagReader) { r.u32() }},
+		{"unterminated string", []byte{tagString, 'a', 'b'}, func(r *tagReader) { r.string() }},
+		{"short arbitrary", []byte{tagArbitrary, 0, 0, 0, 9, 1}, func(r *tagReader) { r.arbitrary() }},
+		{"empty", nil, func(r *tagReader) { r.bool() }},
+	}
+	for _, tt := range tests {
+		r := &tagReader{data: tt.data}
+		tt.read(r)
+		if r.err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+		// Later reads are no-ops once an error is kept.
+		first := r.err
+		r.u32()
+		if r.err != first {
+			t.Errorf("%s: error changed from %v to %v", tt.name, first, r.err)
+		}
+	}
+}
+
+// writeCard writes a card as the server does for protocol version 32.
+func writeCard(w *tagWriter, index uint32, name string, profiles []Profile, active string, props map[string]string) {
+	w.u32(index)
+	w.string(name)
+	w.u32(3) // owner module
+	w.string("module-bluez5-card.c")
+	w.u32(uint32(len(profiles)))
+	for _, p := range profiles {
+		w.string(p.Name)
+		w.string(p.Description)
+		w.u32(p.Sinks)
+		w.u32(p.Sources)
+		w.u32(p.Priority)
+		if p.Available {
+			w.u32(1)
+		} else {
+			w.u32(0)
+		}
+	}
+	w.string(active)
+	w.propList(props)
+	w.u32(0) // ports
+}
+
+func TestCardsAndSetCardProfile(t *testing.T) {
+	profiles := []Profile{
+		{Name: "a2dp_sink", Description: "High Fidelity Playback (A2DP Sink)", Sinks: 1, Priority: 40, Available: true},
+		{Name: "headset_head_unit", Description: "Headset Head Unit (HSP/HFP)", Sinks: 1, Sources: 1, Priority: 30, Available: true},
+		{Name: "off", Description: "Off"},
+	}
+	var setCard, setProfile string
+	s, conn := newFakeServer(t, handshake(t, protocolVersion, func(command uint32, r *tagReader) (*tagWriter, uint32) {
+		switch command {
+		case commandGetCardInfoList:
+			w := &tagWriter{}
+			writeCard(w, 0, "alsa_card.pci-0000_00_1f.3", nil, "", map[string]string{})
+			writeCard(w, 1, "bluez_card.2C_41_A1_49_37_CF", profiles, "headset_head_unit", map[string]string{"device.string": "2C:41:A1:49:37:CF"})
+			return w, 0
+		case commandSetCardProfile:
+			if index := r.u32(); index != invalidIndex {
+				t.Errorf("card index = %d, want invalid index", index)
+			}
+			setCard, setProfile = r.string(), r.string()
+			if setCard != "bluez_card.2C_41_A1_49_37_CF" {
+				return nil, ErrNoEntity
+			}
+			return nil, 0
+		}
+		t.Errorf("unexpected command %d", command)
+		return nil, ErrCommand
+	}))
+	defer s.close()
+	c, err := NewClient(conn, nil)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer c.Close()
+
+	cards, err := c.Cards()
+	if err != nil {
+		t.Fatalf("Cards failed: %v", err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("got %d cards, want 2", len(cards))
+	}
+	card, err := c.BluetoothCard("2C:41:A1:49:37:CF")
+	if err != nil {
+		t.Fatalf("BluetoothCard failed: %v", err)
+	}
+	if card.Index != 1 || card.ActiveProfile != "headset_head_unit" || !reflect.DeepEqual(card.Profiles, profiles) {
+		t.Errorf("card = %+v", card)
+	}
+	if p, err := card.FindProfile("a2dp"); err != nil || p.Name != "a2dp_sink" {
+		t.Errorf("FindProfile(a2dp) = %q, %v", p.Name, err)
+	}
+
+	if err := c.SetCardProfile(card.Name, "a2dp_sink"); err != nil {
+		t.Fatalf("SetCardProfile failed: %v", err)
+	}
+	if setCard != card.Name || setProfile != "a2dp_sink" {
+		t.Errorf("server got card=%q profile=%q", setCard, setProfile)
+	}
+
+	err = c.SetCardProfile("bluez_card.00_00_00_00_00_00", "a2dp_sink")
+	perr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("SetCardProfile error = %v, want *Error", err)
+	}
+	if perr.Command != commandSetCardProfile || perr.Code != ErrNoEntity {
+		t.Errorf("error = %+v", perr)
+	}
+	if want := "pulseaudio command 90 failed: no such entity"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	s, conn := newFakeServer(t, handshake(t, protocolVersion, func(command uint32, r *tagReader) (*tagWriter, uint32) {
+		if command != commandGetServerInfo {
+			t.Errorf("unexpected command %d", command)
+			return nil, ErrCommand
+		}
+		// In the order protocol-native.c sends them.
+		w := &tagWriter{}
+		w.string("pulseaudio")
+		w.string("16.1")
+		w.string("jonathan")
+		w.string("laptop")
+		w.buf.Write([]byte{tagSampleSpec, 3, 2, 0, 0, 0xac, 0x44})
+		w.string("bluez_sink.2C_41_A1_49_37_CF.a2dp_sink")
+		w.string("alsa_input.pci-0000_00_1f.3.analog-stereo")
+		return w, 0
+	}))
+	defer s.close()
+	c, err := NewClient(conn, nil)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer c.Close()
+
+	info, err := c.ServerInfo()
+	if err != nil {
+		t.Fatalf("ServerInfo failed: %v", err)
+	}
+	want := ServerInfo{
+		ServerName:    "pulseaudio",
+		ServerVersion: "16.1",
+		DefaultSink:   "bluez_sink.2C_41_A1_49_37_CF.a2dp_sink",
+		DefaultSource: "alsa_input.pci-0000_00_1f.3.analog-stereo",
+	}
+	if info != want {
+		t.Errorf("info = %+v, want %+v", info, want)
+	}
+}
@@ -0,0 +1,60 @@
+package pulseaudio
+
+import "fmt"
+
+// Error codes returned by the server.
+// https://gitlab.freedesktop.org/pulseaudio/pulseaudio/-/blob/master/src/pulse/def.h
+const (
+	ErrAccess       uint32 = 1
+	ErrCommand      uint32 = 2
+	ErrInvalid      uint32 = 3
+	ErrExist        uint32 = 4
+	ErrNoEntity     uint32 = 5
+	ErrProtocol     uint32 = 7
+	ErrTimeout      uint32 = 8
+	ErrAuthKey      uint32 = 9
+	ErrNotSupported uint32 = 19
+)
+
+var errorMessages = map[uint32]string{
+	ErrAccess:       "access denied",
+	ErrCommand:      "unknown command",
+	ErrInvalid:      "invalid argument",
+	ErrExist:        "entity exists",
+	ErrNoEntity:     "no such entity",
+	6:               "connection refused",
+	ErrProtocol:     "protocol error",
+	ErrTimeout:      "timeout",
+	ErrAuthKey:      "no authentication key",
+	10:              "internal error",
+	11:              "connection terminated",
+	12:              "entity killed",
+	13:              "invalid server",
+	14:              "module initialization failed",
+	15:              "bad state",
+	16:              "no data",
+	17:              "incompatible protocol version",
+	18:              "too large",
+	ErrNotSupported: "not supported",
+	20:              "unknown error code",
+	21:              "no such extension",
+	22:              "obsolete functionality",
+	23:              "missing implementation",
+	24:              "client forked",
+	25:              "input/output error",
+	26:              "device or resource busy",
+}
+
+// Error is returned when the server replies to a command with an error.
+type Error struct {
+	Command uint32
+	Code    uint32
+}
+
+func (e *Error) Error() string {
+	message, ok := errorMessages[e.Code]
+	if !ok {
+		message = fmt.Sprintf("error code %d", e.Code)
+	}
+	return fmt.Sprintf("pulseaudio command %d failed: %s", e.Command, message)
+}
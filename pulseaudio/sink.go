@@ -0,0 +1,311 @@
+package pulseaudio
+
+// ServerInfo holds the defaults of the sound server.
+type ServerInfo struct {
+	ServerName    string
+	ServerVersion string
+	DefaultSink   string
+	DefaultSource string
+}
+
+// Sink is an audio output, ie: speakers or a bluetooth headset.
+type Sink struct {
+	Index       uint32
+	Name        string
+	Description string
+	Card        uint32
+	Properties  map[string]string
+}
+
+// Source is an audio input. Every sink also has a monitor source, these
+// have MonitorOf set to the index of the sink.
+type Source struct {
+	Index       uint32
+	Name        string
+	Description string
+	Card        uint32
+	MonitorOf   uint32
+	Properties  map[string]string
+}
+
+// IsMonitor reports whether the source is the monitor of a sink rather
+// than a real input.
+func (s Source) IsMonitor() bool {
+	return s.MonitorOf != invalidIndex
+}
+
+// Stream is a playback stream (sink input) or a recording stream (source
+// output) and the sink or source it is attached to.
+type Stream struct {
+	Index      uint32
+	Name       string
+	Device     uint32
+	Properties map[string]string
+}
+
+// ServerInfo returns the server information, including the default sink
+// and source.
+func (c *Client) ServerInfo() (ServerInfo, error) {
+	r, err := c.request(commandGetServerInfo, &tagWriter{})
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	info := ServerInfo{}
+	info.ServerName = r.string()
+	info.ServerVersion = r.string()
+	r.string() // user name
+	r.string() // host name
+	r.sampleSpec()
+	info.DefaultSink = r.string()
+	info.DefaultSource = r.string()
+	return info, r.err
+}
+
+// Sinks returns all the sinks known to the server.
+func (c *Client) Sinks() ([]Sink, error) {
+	r, err := c.request(commandGetSinkInfoList, &tagWriter{})
+	if err != nil {
+		return nil, err
+	}
+	sinks := []Sink{}
+	for !r.empty() {
+		s := Sink{Card: invalidIndex}
+		s.Index = r.u32()
+		s.Name = r.string()
+		s.Description = r.string()
+		r.sampleSpec()
+		r.channelMap()
+		r.u32() // owner module
+		r.cvolume()
+		r.bool()   // mute
+		r.u32()    // monitor source
+		r.string() // monitor source name
+		r.u64(tagUsec)
+		r.string() // driver
+		r.u32()    // flags
+		s.Card = r.deviceTrailer(c.version, 21, &s.Properties)
+		if r.err != nil {
+			return nil, r.err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// Sources returns all the sources known to the server, including monitors.
+func (c *Client) Sources() ([]Source, error) {
+	r, err := c.request(commandGetSourceInfoList, &tagWriter{})
+	if err != nil {
+		return nil, err
+	}
+	sources := []Source{}
+	for !r.empty() {
+		s := Source{Card: invalidIndex}
+		s.Index = r.u32()
+		s.Name = r.string()
+		s.Description = r.string()
+		r.sampleSpec()
+		r.channelMap()
+		r.u32() // owner module
+		r.cvolume()
+		r.bool() // mute
+		s.MonitorOf = r.u32()
+		r.string() // monitor of sink name
+		r.u64(tagUsec)
+		r.string() // driver
+		r.u32()    // flags
+		s.Card = r.deviceTrailer(c.version, 22, &s.Properties)
+		if r.err != nil {
+			return nil, r.err
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// deviceTrailer reads the fields sinks and sources share after their flags
+// and returns the card index. Formats were added to sinks and sources in
+// different protocol versions, given by formatsVersion.
+func (r *tagReader) deviceTrailer(version, formatsVersion uint32, props *map[string]string) uint32 {
+	card := invalidIndex
+	if version >= 13 {
+		*props = r.propList()
+		r.u64(tagUsec) // configured latency
+	}
+	if version >= 15 {
+		r.volume() // base volume
+		r.u32()    // state
+		r.u32()    // volume steps
+		card = r.u32()
+	}
+	if version >= 16 {
+		ports := r.u32()
+		for i := uint32(0); i < ports && r.err == nil; i++ {
+			r.string() // name
+			r.string() // description
+			r.u32()    // priority
+			if version >= 24 {
+				r.u32() // available
+			}
+		}
+		r.string() // active port
+	}
+	if version >= formatsVersion {
+		formats := r.u8()
+		for i := byte(0); i < formats && r.err == nil; i++ {
+			r.formatInfo()
+		}
+	}
+	return card
+}
+
+// CardSink returns the first sink belonging to a card.
+func (c *Client) CardSink(card uint32) (Sink, bool, error) {
+	sinks, err := c.Sinks()
+	if err != nil {
+		return Sink{}, false, err
+	}
+	for _, s := range sinks {
+		if s.Card == card {
+			return s, true, nil
+		}
+	}
+	return Sink{}, false, nil
+}
+
+// CardSource returns the first source belonging to a card that isn't a
+// monitor, only headset profiles have a source.
+func (c *Client) CardSource(card uint32) (Source, bool, error) {
+	sources, err := c.Sources()
+	if err != nil {
+		return Source{}, false, err
+	}
+	for _, s := range sources {
+		if s.Card == card && !s.IsMonitor() {
+			return s, true, nil
+		}
+	}
+	return Source{}, false, nil
+}
+
+// SinkInputs returns all the playback streams.
+func (c *Client) SinkInputs() ([]Stream, error) {
+	r, err := c.request(commandGetSinkInputInfoList, &tagWriter{})
+	if err != nil {
+		return nil, err
+	}
+	streams := []Stream{}
+	for !r.empty() {
+		s := Stream{}
+		s.Index = r.u32()
+		s.Name = r.string()
+		r.u32() // owner module
+		r.u32() // client
+		s.Device = r.u32()
+		r.sampleSpec()
+		r.channelMap()
+		r.cvolume()
+		r.u64(tagUsec) // buffer latency
+		r.u64(tagUsec) // sink latency
+		r.string()     // resample method
+		r.string()     // driver
+		if c.version >= 11 {
+			r.bool() // mute
+		}
+		if c.version >= 13 {
+			s.Properties = r.propList()
+		}
+		if c.version >= 19 {
+			r.bool() // corked
+		}
+		if c.version >= 20 {
+			r.bool() // has volume
+			r.bool() // volume writable
+		}
+		if c.version >= 21 {
+			r.formatInfo()
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		streams = append(streams, s)
+	}
+	return streams, nil
+}
+
+// SourceOutputs returns all the recording streams.
+func (c *Client) SourceOutputs() ([]Stream, error) {
+	r, err := c.request(commandGetSourceOutputInfoList, &tagWriter{})
+	if err != nil {
+		return nil, err
+	}
+	streams := []Stream{}
+	for !r.empty() {
+		s := Stream{}
+		s.Index = r.u32()
+		s.Name = r.string()
+		r.u32() // owner module
+		r.u32() // client
+		s.Device = r.u32()
+		r.sampleSpec()
+		r.channelMap()
+		r.u64(tagUsec) // buffer latency
+		r.u64(tagUsec) // source latency
+		r.string()     // resample method
+		r.string()     // driver
+		if c.version >= 13 {
+			s.Properties = r.propList()
+		}
+		if c.version >= 19 {
+			r.bool() // corked
+		}
+		if c.version >= 22 {
+			r.cvolume()
+			r.bool() // mute
+			r.bool() // has volume
+			r.bool() // volume writable
+			r.formatInfo()
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		streams = append(streams, s)
+	}
+	return streams, nil
+}
+
+// SetDefaultSink sets the sink new playback streams are attached to.
+func (c *Client) SetDefaultSink(name string) error {
+	w := &tagWriter{}
+	w.string(name)
+	_, err := c.request(commandSetDefaultSink, w)
+	return err
+}
+
+// SetDefaultSource sets the source new recording streams are attached to.
+func (c *Client) SetDefaultSource(name string) error {
+	w := &tagWriter{}
+	w.string(name)
+	_, err := c.request(commandSetDefaultSource, w)
+	return err
+}
+
+// MoveSinkInput moves a playback stream to another sink.
+func (c *Client) MoveSinkInput(stream, sink uint32) error {
+	w := &tagWriter{}
+	w.u32(stream)
+	w.u32(sink)
+	w.stringOrNull("")
+	_, err := c.request(commandMoveSinkInput, w)
+	return err
+}
+
+// MoveSourceOutput moves a recording stream to another source.
+func (c *Client) MoveSourceOutput(stream, source uint32) error {
+	w := &tagWriter{}
+	w.u32(stream)
+	w.u32(source)
+	w.stringOrNull("")
+	_, err := c.request(commandMoveSourceOutput, w)
+	return err
+}
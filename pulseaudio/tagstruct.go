@@ -0,0 +1,225 @@
+package pulseaudio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Tags prefix every value in a pulseaudio tagstruct.
+// https://gitlab.freedesktop.org/pulseaudio/pulseaudio/-/blob/master/src/pulsecore/tagstruct.h
+const (
+	tagString     byte   = 't'
+	tagStringNull byte   = 'N'
+	tagU32        byte   = 'L'
+	tagU8         byte   = 'B'
+	tagU64        byte   = 'R'
+	tagS64        byte   = 'r'
+	tagSampleSpec byte   = 'a'
+	tagArbitrary  byte   = 'x'
+	tagBoolTrue   byte   = '1'
+	tagBoolFalse  byte   = '0'
+	tagTimeval    byte   = 'T'
+	tagUsec       byte   = 'U'
+	tagChannelMap byte   = 'm'
+	tagCVolume    byte   = 'v'
+	tagPropList   byte   = 'P'
+	tagVolume     byte   = 'V'
+	tagFormatInfo byte   = 'f'
+	invalidIndex  uint32 = 0xffffffff
+)
+
+// tagWriter builds the payload for a packet sent to the server.
+type tagWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *tagWriter) u32(v uint32) {
+	w.buf.WriteByte(tagU32)
+	binary.Write(&w.buf, binary.BigEndian, v)
+}
+
+func (w *tagWriter) string(v string) {
+	w.buf.WriteByte(tagString)
+	w.buf.WriteString(v)
+	w.buf.WriteByte(0)
+}
+
+// stringOrNull writes a null string for "", which the server treats as
+// "not set" for fields like a card or sink name.
+func (w *tagWriter) stringOrNull(v string) {
+	if v == "" {
+		w.buf.WriteByte(tagStringNull)
+		return
+	}
+	w.string(v)
+}
+
+func (w *tagWriter) arbitrary(v []byte) {
+	w.buf.WriteByte(tagArbitrary)
+	binary.Write(&w.buf, binary.BigEndian, uint32(len(v)))
+	w.buf.Write(v)
+}
+
+func (w *tagWriter) propList(props map[string]string) {
+	w.buf.WriteByte(tagPropList)
+	for k, v := range props {
+		value := append([]byte(v), 0)
+		w.string(k)
+		w.u32(uint32(len(value)))
+		w.arbitrary(value)
+	}
+	w.buf.WriteByte(tagStringNull)
+}
+
+func (w *tagWriter) bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// tagReader reads values from a packet received from the server, the
+// first error encountered is kept and every later read is a no-op.
+type tagReader struct {
+	data []byte
+	err  error
+}
+
+func (r *tagReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("malformed tagstruct: "+format, args...)
+	}
+}
+
+func (r *tagReader) empty() bool {
+	return r.err != nil || len(r.data) == 0
+}
+
+func (r *tagReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.fail("expected %d bytes, only %d remaining", n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *tagReader) tag(expected ...byte) byte {
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	for _, e := range expected {
+		if b[0] == e {
+			return b[0]
+		}
+	}
+	r.fail("unexpected tag %q, expected one of %q", b[0], expected)
+	return 0
+}
+
+func (r *tagReader) u32() uint32 {
+	r.tag(tagU32)
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *tagReader) u8() byte {
+	r.tag(tagU8)
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *tagReader) u64(tag byte) uint64 {
+	r.tag(tag)
+	b := r.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *tagReader) bool() bool {
+	return r.tag(tagBoolTrue, tagBoolFalse) == tagBoolTrue
+}
+
+func (r *tagReader) string() string {
+	if r.tag(tagString, tagStringNull) != tagString {
+		return ""
+	}
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.fail("unterminated string")
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+func (r *tagReader) arbitrary() []byte {
+	r.tag(tagArbitrary)
+	b := r.take(4)
+	if b == nil {
+		return nil
+	}
+	return r.take(int(binary.BigEndian.Uint32(b)))
+}
+
+func (r *tagReader) propList() map[string]string {
+	r.tag(tagPropList)
+	props := map[string]string{}
+	for r.err == nil {
+		if len(r.data) > 0 && r.data[0] == tagStringNull {
+			r.data = r.data[1:]
+			break
+		}
+		key := r.string()
+		r.u32()
+		value := r.arbitrary()
+		props[key] = string(bytes.TrimRight(value, "\x00"))
+	}
+	return props
+}
+
+// channels reads the channel count prefixing channel maps and volumes.
+func (r *tagReader) channels(tag byte) int {
+	r.tag(tag)
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	return int(b[0])
+}
+
+func (r *tagReader) sampleSpec() {
+	r.tag(tagSampleSpec)
+	r.take(6)
+}
+
+func (r *tagReader) channelMap() {
+	r.take(r.channels(tagChannelMap))
+}
+
+func (r *tagReader) cvolume() {
+	r.take(r.channels(tagCVolume) * 4)
+}
+
+func (r *tagReader) volume() {
+	r.tag(tagVolume)
+	r.take(4)
+}
+
+func (r *tagReader) formatInfo() {
+	r.tag(tagFormatInfo)
+	r.u8()
+	r.propList()
+}
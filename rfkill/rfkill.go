@@ -0,0 +1,229 @@
+// Package rfkill reads and changes the radio block state of devices through
+// /dev/rfkill, which is what stops a soft blocked bluetooth adapter being
+// powered on.
+// https://www.kernel.org/doc/html/latest/driver-api/rfkill.html
+package rfkill
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"syscall"
+)
+
+// DevicePath is the rfkill control device.
+const DevicePath = "/dev/rfkill"
+
+// eventSize is the size of the original struct rfkill_event, newer kernels
+// have a larger event but only fill in as much as is read.
+const eventSize = 8
+
+// Type is the radio type of an rfkill device.
+type Type uint8
+
+// Radio types, from include/uapi/linux/rfkill.h.
+const (
+	TypeAll       Type = 0
+	TypeWLAN      Type = 1
+	TypeBluetooth Type = 2
+	TypeUWB       Type = 3
+	TypeWiMAX     Type = 4
+	TypeWWAN      Type = 5
+	TypeGPS       Type = 6
+	TypeFM        Type = 7
+	TypeNFC       Type = 8
+)
+
+// Op is the operation an event describes.
+type Op uint8
+
+// Event operations, the kernel sends an OpAdd for every existing device
+// when /dev/rfkill is opened.
+const (
+	OpAdd       Op = 0
+	OpDel       Op = 1
+	OpChange    Op = 2
+	OpChangeAll Op = 3
+)
+
+// Event is a struct rfkill_event read from or written to /dev/rfkill.
+type Event struct {
+	Index uint32
+	Type  Type
+	Op    Op
+	Soft  bool
+	Hard  bool
+}
+
+// MarshalBinary encodes the event in the layout the kernel expects.
+func (e Event) MarshalBinary() ([]byte, error) {
+	b := make([]byte, eventSize)
+	binary.LittleEndian.PutUint32(b, e.Index)
+	b[4] = byte(e.Type)
+	b[5] = byte(e.Op)
+	b[6] = boolByte(e.Soft)
+	b[7] = boolByte(e.Hard)
+	return b, nil
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ReadEvents reads events from r until io.EOF. r is usually /dev/rfkill
+// but can be any recorded stream of events.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	b := make([]byte, eventSize)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return events, fmt.Errorf("unable to read rfkill event: %v", err)
+		}
+		events = append(events, Event{
+			Index: binary.LittleEndian.Uint32(b),
+			Type:  Type(b[4]),
+			Op:    Op(b[5]),
+			Soft:  b[6] != 0,
+			Hard:  b[7] != 0,
+		})
+	}
+}
+
+// State folds events into the current state of each device, keyed by
+// index. Devices that have been removed are dropped.
+func State(events []Event) map[uint32]Event {
+	state := map[uint32]Event{}
+	for _, e := range events {
+		switch e.Op {
+		case OpAdd, OpChange:
+			state[e.Index] = e
+		case OpDel:
+			delete(state, e.Index)
+		case OpChangeAll:
+			for i, s := range state {
+				if e.Type == TypeAll || e.Type == s.Type {
+					s.Soft = e.Soft
+					state[i] = s
+				}
+			}
+		}
+	}
+	return state
+}
+
+// Device is an rfkill device and its current block state.
+type Device struct {
+	Index uint32
+	Name  string
+	Type  Type
+	Soft  bool
+	Hard  bool
+}
+
+// Blocked reports whether the radio is blocked in any way.
+func (d Device) Blocked() bool {
+	return d.Soft || d.Hard
+}
+
+// String returns the block state as shown by `sluez status`.
+func (d Device) String() string {
+	switch {
+	case d.Hard:
+		return "hard-blocked"
+	case d.Soft:
+		return "soft-blocked"
+	}
+	return "unblocked"
+}
+
+// fdReader reads from a non-blocking file descriptor, returning io.EOF
+// once there is nothing left to read.
+type fdReader int
+
+func (fd fdReader) Read(p []byte) (int, error) {
+	n, err := syscall.Read(int(fd), p)
+	if err == syscall.EAGAIN || n == 0 && err == nil {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Devices returns every rfkill device. The state comes from the events the
+// kernel queues when /dev/rfkill is opened and the names from sysfs.
+func Devices() ([]Device, error) {
+	fd, err := syscall.Open(DevicePath, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %v", DevicePath, err)
+	}
+	defer syscall.Close(fd)
+	events, err := ReadEvents(fdReader(fd))
+	if err != nil {
+		return nil, err
+	}
+	devices := []Device{}
+	for _, e := range State(events) {
+		devices = append(devices, Device{
+			Index: e.Index,
+			Name:  deviceName(e.Index),
+			Type:  e.Type,
+			Soft:  e.Soft,
+			Hard:  e.Hard,
+		})
+	}
+	return devices, nil
+}
+
+func deviceName(index uint32) string {
+	name, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/rfkill/rfkill%d/name", index))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(name))
+}
+
+// Find returns the rfkill device for a bluetooth adapter, ie: "hci0".
+func Find(adapterName string) (Device, bool, error) {
+	devices, err := Devices()
+	if err != nil {
+		return Device{}, false, err
+	}
+	d, ok := findAdapter(devices, adapterName)
+	return d, ok, nil
+}
+
+// findAdapter returns the bluetooth device named after an adapter, rfkill
+// indexes are unrelated to adapter numbers so only the name is compared.
+func findAdapter(devices []Device, adapterName string) (Device, bool) {
+	for _, d := range devices {
+		if d.Type == TypeBluetooth && d.Name == adapterName {
+			return d, true
+		}
+	}
+	return Device{}, false
+}
+
+// Unblock removes the soft block from a device, this requires write access
+// to /dev/rfkill. A hard block can only be removed by the hardware switch.
+func Unblock(index uint32) error {
+	f, err := syscall.Open(DevicePath, syscall.O_WRONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(f)
+	b, _ := Event{Index: index, Op: OpChange, Soft: false}.MarshalBinary()
+	if _, err := syscall.Write(f, b); err != nil {
+		return err
+	}
+	return nil
+}
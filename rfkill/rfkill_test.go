@@ -0,0 +1,189 @@
+package rfkill
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Events recorded from /dev/rfkill, as struct rfkill_event: a little endian
+// index followed by the type, op, soft and hard bytes.
+var (
+	recordedWLAN      = []byte{0, 0, 0, 0, 1, 0, 0, 0}
+	recordedBluetooth = []byte{1, 0, 0, 0, 2, 0, 1, 0}
+	recordedBoth      = []byte{2, 0, 0, 0, 2, 0, 1, 1}
+	recordedHard      = []byte{3, 0, 0, 0, 2, 0, 0, 1}
+	recordedUnblocked = []byte{1, 0, 0, 0, 2, 2, 0, 0}
+	recordedDel       = []byte{0, 0, 0, 0, 1, 1, 0, 0}
+	recordedBlockAll  = []byte{0, 0, 0, 0, 2, 3, 1, 0}
+	recordedLargeIdx  = []byte{0x01, 0x02, 0, 0, 2, 0, 0, 0}
+)
+
+func concat(records ...[]byte) []byte {
+	return bytes.Join(records, nil)
+}
+
+func TestReadEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Event
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: nil,
+			want: []Event{},
+		},
+		{
+			name: "unblocked",
+			data: recordedWLAN,
+			want: []Event{{Index: 0, Type: TypeWLAN, Op: OpAdd}},
+		},
+		{
+			name: "soft blocked",
+			data: recordedBluetooth,
+			want: []Event{{Index: 1, Type: TypeBluetooth, Op: OpAdd, Soft: true}},
+		},
+		{
+			name: "hard blocked",
+			data: recordedHard,
+			want: []Event{{Index: 3, Type: TypeBluetooth, Op: OpAdd, Hard: true}},
+		},
+		{
+			name: "soft and hard blocked",
+			data: recordedBoth,
+			want: []Event{{Index: 2, Type: TypeBluetooth, Op: OpAdd, Soft: true, Hard: true}},
+		},
+		{
+			name: "little endian index",
+			data: recordedLargeIdx,
+			want: []Event{{Index: 0x0201, Type: TypeBluetooth, Op: OpAdd}},
+		},
+		{
+			name: "several events",
+			data: concat(recordedWLAN, recordedBluetooth, recordedUnblocked),
+			want: []Event{
+				{Index: 0, Type: TypeWLAN, Op: OpAdd},
+				{Index: 1, Type: TypeBluetooth, Op: OpAdd, Soft: true},
+				{Index: 1, Type: TypeBluetooth, Op: OpChange},
+			},
+		},
+		{
+			name:    "truncated event",
+			data:    recordedBluetooth[:5],
+			want:    []Event{},
+			wantErr: true,
+		},
+		{
+			name:    "truncated after a complete event",
+			data:    concat(recordedWLAN, recordedBluetooth[:3]),
+			want:    []Event{{Index: 0, Type: TypeWLAN, Op: OpAdd}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := ReadEvents(bytes.NewReader(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: events = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventMarshalBinary(t *testing.T) {
+	for _, record := range [][]byte{recordedWLAN, recordedBluetooth, recordedBoth, recordedHard, recordedLargeIdx} {
+		events, err := ReadEvents(bytes.NewReader(record))
+		if err != nil || len(events) != 1 {
+			t.Fatalf("ReadEvents(%v) = %v, %v", record, events, err)
+		}
+		b, _ := events[0].MarshalBinary()
+		if !bytes.Equal(b, record) {
+			t.Errorf("MarshalBinary(%+v) = %v, want %v", events[0], b, record)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want map[uint32]Event
+	}{
+		{
+			name: "later event overrides earlier",
+			data: concat(recordedBluetooth, recordedUnblocked),
+			want: map[uint32]Event{1: {Index: 1, Type: TypeBluetooth, Op: OpChange}},
+		},
+		{
+			name: "removed device is dropped",
+			data: concat(recordedWLAN, recordedBluetooth, recordedDel),
+			want: map[uint32]Event{1: {Index: 1, Type: TypeBluetooth, Op: OpAdd, Soft: true}},
+		},
+		{
+			name: "change all only changes soft block of the type",
+			data: concat(recordedWLAN, recordedHard, recordedBlockAll),
+			want: map[uint32]Event{
+				0: {Index: 0, Type: TypeWLAN, Op: OpAdd},
+				3: {Index: 3, Type: TypeBluetooth, Op: OpAdd, Soft: true, Hard: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		events, err := ReadEvents(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := State(events); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: state = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceString(t *testing.T) {
+	tests := []struct {
+		device  Device
+		want    string
+		blocked bool
+	}{
+		{Device{}, "unblocked", false},
+		{Device{Soft: true}, "soft-blocked", true},
+		{Device{Hard: true}, "hard-blocked", true},
+		{Device{Soft: true, Hard: true}, "hard-blocked", true},
+	}
+	for _, tt := range tests {
+		if got := tt.device.String(); got != tt.want {
+			t.Errorf("%+v: String() = %q, want %q", tt.device, got, tt.want)
+		}
+		if got := tt.device.Blocked(); got != tt.blocked {
+			t.Errorf("%+v: Blocked() = %t, want %t", tt.device, got, tt.blocked)
+		}
+	}
+}
+
+func TestFindAdapter(t *testing.T) {
+	devices := []Device{
+		{Index: 0, Name: "phy0", Type: TypeWLAN},
+		{Index: 1, Name: "hci1", Type: TypeWLAN},
+		{Index: 4, Name: "hci1", Type: TypeBluetooth, Soft: true},
+		{Index: 2, Name: "hci0", Type: TypeBluetooth},
+	}
+	tests := []struct {
+		adapter   string
+		wantIndex uint32
+		wantOK    bool
+	}{
+		{"hci0", 2, true},
+		{"hci1", 4, true},
+		{"hci2", 0, false},
+		{"phy0", 0, false},
+	}
+	for _, tt := range tests {
+		d, ok := findAdapter(devices, tt.adapter)
+		if ok != tt.wantOK || d.Index != tt.wantIndex {
+			t.Errorf("findAdapter(%q) = index %d, %t, want index %d, %t", tt.adapter, d.Index, ok, tt.wantIndex, tt.wantOK)
+		}
+	}
+}